@@ -44,28 +44,22 @@ func (a *CopyAction) Execute(ctx context.Context, runtime *types.Runtime) error
 	var fileSize int64
 
 	if a.Artifact != "" {
-		// ARTIFACTS: Read into memory buffer
-		art, err := runtime.ArtifactMgr.Get(a.Artifact)
+		// ARTIFACTS: Streamed from disk, checksum cached by the manager
+		var err error
+		fileSize, err = runtime.ArtifactMgr.Size(a.Artifact)
 		if err != nil {
 			return fmt.Errorf("failed to get artifact %s: %w", a.Artifact, err)
 		}
-		defer art.Close()
 
-		// Read entire artifact into memory
-		data, err := io.ReadAll(art)
+		localChecksum, err = runtime.ArtifactMgr.Checksum(a.Artifact)
 		if err != nil {
-			return fmt.Errorf("failed to read artifact: %w", err)
+			return fmt.Errorf("failed to get artifact %s: %w", a.Artifact, err)
 		}
 
-		fileSize = int64(len(data))
-
-		// Calculate checksum from buffer
-		h := sha256.New()
-		h.Write(data)
-		localChecksum = hex.EncodeToString(h.Sum(nil))
-
-		// Create reader from buffer
-		reader = io.NopCloser(bytes.NewReader(data))
+		reader, err = runtime.ArtifactMgr.Get(a.Artifact)
+		if err != nil {
+			return fmt.Errorf("failed to get artifact %s: %w", a.Artifact, err)
+		}
 		srcDesc = fmt.Sprintf("artifact:%s", a.Artifact)
 
 	} else if a.Src != "" {
@@ -148,7 +142,7 @@ func (a *CopyAction) Execute(ctx context.Context, runtime *types.Runtime) error
 		}
 
 		// Content matches but permissions differ - just chmod
-		chmodCmd := fmt.Sprintf("chmod %o %s", a.Mode, dst)
+		chmodCmd := ssh.Command("chmod", ssh.Mode(a.Mode), "--", dst)
 		if err := sess.Run(ctx, chmodCmd, runtime.Stdout, runtime.Stderr); err != nil {
 			return fmt.Errorf("failed to update permissions: %w", err)
 		}
@@ -157,7 +151,8 @@ func (a *CopyAction) Execute(ctx context.Context, runtime *types.Runtime) error
 	}
 
 	// Copy file (checksums differ, file doesn't exist, or tool missing)
-	if err := sess.CopyFile(ctx, reader, dst, a.Mode); err != nil {
+	content := newProgressReader(reader, runtime.Stdout, dst, fileSize)
+	if err := sess.CopyFile(ctx, content, dst, a.Mode); err != nil {
 		return fmt.Errorf("failed to copy %s to %s: %w", srcDesc, dst, err)
 	}
 
@@ -217,7 +212,7 @@ func getRemoteChecksum(ctx context.Context, sess ssh.Session, remotePath string)
 	var stdout bytes.Buffer
 
 	// Use shell command that handles missing file gracefully
-	cmd := fmt.Sprintf("sha256sum %s 2>/dev/null || echo NOTFOUND", remotePath)
+	cmd := ssh.Command("sha256sum", "--", remotePath) + " 2>/dev/null || echo NOTFOUND"
 
 	err := sess.Run(ctx, cmd, &stdout, io.Discard)
 	if err != nil {
@@ -249,7 +244,7 @@ func getRemotePermissions(ctx context.Context, sess ssh.Session, remotePath stri
 
 	// Use stat command to get permissions in octal format
 	// %a gives permissions in octal (e.g., 644)
-	cmd := fmt.Sprintf("stat -c '%%a' %s 2>/dev/null", remotePath)
+	cmd := ssh.Command("stat", "-c", "%a", "--", remotePath) + " 2>/dev/null"
 
 	err := sess.Run(ctx, cmd, &stdout, io.Discard)
 	if err != nil {
@@ -168,6 +168,10 @@ func (m *mockSession) Run(ctx context.Context, cmd string, stdout, stderr io.Wri
 	return nil
 }
 
+func (m *mockSession) RunWithInput(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return m.Run(ctx, cmd, stdout, stderr)
+}
+
 func (m *mockSession) CopyFile(ctx context.Context, content io.Reader, remotePath string, mode uint32) error {
 	if m.copyFileFunc != nil {
 		return m.copyFileFunc(ctx, content, remotePath, mode)
@@ -10,6 +10,7 @@ import (
 
 	"github.com/SoftKiwiGames/hades/config"
 	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/ssh"
 	"github.com/SoftKiwiGames/hades/hades/types"
 )
 
@@ -95,8 +96,9 @@ func (a *GpgAction) Execute(ctx context.Context, runtime *types.Runtime) error {
 		}
 
 		// Run gpg --dearmor to convert ASCII to binary
-		dearmorCmd := fmt.Sprintf("gpg --yes --dearmor -o %s < %s && chmod %o %s && rm -f %s",
-			path, tmpPath, a.Mode, path, tmpPath)
+		dearmorCmd := ssh.Command("gpg", "--yes", "--dearmor", "-o", path) + " < " + ssh.Quote(tmpPath) +
+			" && " + ssh.Command("chmod", ssh.Mode(a.Mode), "--", path) +
+			" && " + ssh.Command("rm", "-f", "--", tmpPath)
 
 		// Use runtime's writers to log the dearmor command output
 		if err := sess.Run(ctx, dearmorCmd, runtime.Stdout, runtime.Stderr); err != nil {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/ssh"
 	"github.com/SoftKiwiGames/hades/hades/types"
 )
 
@@ -32,7 +33,7 @@ func (a *MkdirAction) Execute(ctx context.Context, runtime *types.Runtime) error
 	path := ExpandEnvVars(a.Path, runtime.Env)
 
 	// Build mkdir command with mode
-	cmd := fmt.Sprintf("mkdir -p %s && chmod %o %s", path, a.Mode, path)
+	cmd := ssh.Command("mkdir", "-p", "--", path) + " && " + ssh.Command("chmod", ssh.Mode(a.Mode), "--", path)
 
 	// Execute command - use runtime's writers to log output
 	if err := sess.Run(ctx, cmd, runtime.Stdout, runtime.Stderr); err != nil {
@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"fmt"
+	"io"
+)
+
+// progressMinSize is the smallest transfer that gets progress lines in the log
+const progressMinSize = 1024 * 1024
+
+// progressReader logs transfer progress to the host log every 10%
+type progressReader struct {
+	reader   io.Reader
+	log      io.Writer
+	desc     string
+	total    int64
+	read     int64
+	reported int64 // Last reported percentage
+}
+
+// newProgressReader wraps reader; small or unknown-size transfers are not reported
+func newProgressReader(reader io.Reader, log io.Writer, desc string, total int64) io.Reader {
+	if total < progressMinSize || log == nil {
+		return reader
+	}
+	return &progressReader{reader: reader, log: log, desc: desc, total: total}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.read += int64(n)
+
+	percent := p.read * 100 / p.total
+	if percent >= p.reported+10 {
+		p.reported = percent - percent%10
+		fmt.Fprintf(p.log, "Transferring %s: %d%% (%s of %s)\n", p.desc, p.reported, formatFileSize(p.read), formatFileSize(p.total))
+	}
+
+	return n, err
+}
@@ -0,0 +1,40 @@
+package actions
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestProgressReader(t *testing.T) {
+	var log bytes.Buffer
+	data := make([]byte, 4*progressMinSize)
+
+	reader := newProgressReader(bytes.NewReader(data), &log, "/opt/app.tar.gz", int64(len(data)))
+	n, err := io.Copy(io.Discard, reader)
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("Expected %d bytes, got %d (%v)", len(data), n, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 10 {
+		t.Errorf("Expected 10 progress lines, got %d:\n%s", len(lines), log.String())
+	}
+	want := "Transferring /opt/app.tar.gz: 100% (4.00 MiB of 4.00 MiB)"
+	if lines[len(lines)-1] != want {
+		t.Errorf("Expected %q, got %q", want, lines[len(lines)-1])
+	}
+}
+
+func TestProgressReader_SmallFile(t *testing.T) {
+	var log bytes.Buffer
+	data := []byte("small config")
+
+	reader := newProgressReader(bytes.NewReader(data), &log, "/etc/app.conf", int64(len(data)))
+	io.Copy(io.Discard, reader)
+
+	if log.Len() != 0 {
+		t.Errorf("Expected no progress for small files, got %q", log.String())
+	}
+}
@@ -0,0 +1,112 @@
+package actions
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+	"github.com/SoftKiwiGames/hades/hades/types"
+)
+
+// hostileNames break or inject commands when interpolated into a shell unquoted
+var hostileNames = []string{
+	"with space",
+	"it's",
+	`"double"`,
+	"$HOME",
+	"$(touch pwned)",
+	"`touch pwned`",
+	"a; touch pwned",
+	"a && touch pwned",
+	"a | cat",
+	"*",
+	"-rf",
+}
+
+func newLocalRuntime(env map[string]string) *types.Runtime {
+	return &types.Runtime{
+		SSHClient: ssh.NewLocalClient(),
+		Env:       env,
+		Stdout:    io.Discard,
+		Stderr:    io.Discard,
+	}
+}
+
+// assertNotInjected fails if any injected `touch pwned` ran
+func assertNotInjected(t *testing.T, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+			t.Errorf("Command injection: %s/pwned was created", dir)
+		}
+	}
+}
+
+func TestMkdirAction_HostilePaths(t *testing.T) {
+	cwd := t.TempDir()
+	t.Chdir(cwd)
+	dir := t.TempDir()
+
+	for _, name := range hostileNames {
+		path := filepath.Join(dir, name, "sub")
+		action := &MkdirAction{Path: path, Mode: 0750}
+
+		if err := action.Execute(context.Background(), newLocalRuntime(nil)); err != nil {
+			t.Errorf("mkdir %q: unexpected error: %v", name, err)
+			continue
+		}
+
+		stat, err := os.Stat(path)
+		if err != nil || !stat.IsDir() {
+			t.Errorf("mkdir %q: expected directory to exist", name)
+			continue
+		}
+		if stat.Mode().Perm() != 0750 {
+			t.Errorf("mkdir %q: expected mode 750, got %o", name, stat.Mode().Perm())
+		}
+	}
+
+	assertNotInjected(t, cwd, dir)
+}
+
+func TestCopyAction_HostilePaths(t *testing.T) {
+	cwd := t.TempDir()
+	t.Chdir(cwd)
+	dir := t.TempDir()
+
+	src := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(src, []byte("port=8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range hostileNames {
+		// Destination comes from env, like a deployed path would
+		runtime := newLocalRuntime(map[string]string{"NAME": name})
+		action := &CopyAction{Src: src, Dst: filepath.Join(dir, "${NAME}", "app.conf"), Mode: 0640}
+		dst := filepath.Join(dir, name, "app.conf")
+
+		// First run copies; second runs sha256sum/stat/chmod against the existing file
+		for run := 0; run < 2; run++ {
+			if err := action.Execute(context.Background(), runtime); err != nil {
+				t.Errorf("copy to %q (run %d): unexpected error: %v", name, run, err)
+			}
+			if run == 0 {
+				os.Chmod(dst, 0600)
+			}
+		}
+
+		data, err := os.ReadFile(dst)
+		if err != nil || string(data) != "port=8080\n" {
+			t.Errorf("copy to %q: expected file content, got %q (%v)", name, data, err)
+			continue
+		}
+		if stat, _ := os.Stat(dst); stat.Mode().Perm() != 0640 {
+			t.Errorf("copy to %q: expected mode 640, got %o", name, stat.Mode().Perm())
+		}
+	}
+
+	assertNotInjected(t, cwd, dir)
+}
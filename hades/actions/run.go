@@ -1,44 +1,185 @@
 package actions
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/ssh"
 	"github.com/SoftKiwiGames/hades/hades/types"
 )
 
 type RunAction struct {
 	Command string
+	Cwd     string
+	Shell   string
+	Env     map[string]string
+	Stdin   *schema.RunStdin
+	User    string
+
+	// Register stores stdout, stderr and the exit code in runtime.Env
+	// as ${Register}, ${Register_STDERR} and ${Register_RC}
+	Register string
 }
 
-func NewRunAction(action *schema.ActionRun) Action {
+func NewRunAction(action *schema.ActionRun, register string) Action {
 	return &RunAction{
-		Command: string(*action),
+		Command: action.Cmd,
+		Cwd:     action.Cwd,
+		Shell:   action.Shell,
+		Env:     action.Env,
+		Stdin:   action.Stdin,
+		User:    action.User,
+
+		Register: register,
 	}
 }
 
 func (a *RunAction) Execute(ctx context.Context, runtime *types.Runtime) error {
+	env := a.env(runtime)
+
+	stdin, err := a.openStdin(runtime, env)
+	if err != nil {
+		return err
+	}
+	if stdin != nil {
+		defer stdin.Close()
+	}
+
+	// Action-local env and user only apply to this command
+	host := runtime.Host
+	if len(a.Env) > 0 {
+		host.Env = maps.Clone(host.Env)
+		if host.Env == nil {
+			host.Env = make(map[string]string)
+		}
+		for name := range a.Env {
+			host.Env[name] = env[name]
+		}
+	}
+	if a.User != "" {
+		host.Become = ExpandEnvVars(a.User, env)
+	}
+
 	// Create SSH session
-	sess, err := runtime.SSHClient.Connect(ctx, runtime.Host)
+	sess, err := runtime.SSHClient.Connect(ctx, host)
 	if err != nil {
 		return fmt.Errorf("failed to connect to host: %w", err)
 	}
 	defer sess.Close()
 
-	// Expand environment variables in the command
-	cmd := ExpandEnvVars(a.Command, runtime.Env)
+	cmd := a.command(env)
 
 	// Execute command - use runtime's stdout/stderr to ensure output goes to logs
-	if err := sess.Run(ctx, cmd, runtime.Stdout, runtime.Stderr); err != nil {
+	stdout, stderr := runtime.Stdout, runtime.Stderr
+	var capturedOut, capturedErr bytes.Buffer
+	if a.Register != "" {
+		stdout = io.MultiWriter(stdout, &capturedOut)
+		stderr = io.MultiWriter(stderr, &capturedErr)
+	}
+
+	err = sess.RunWithInput(ctx, cmd, stdin, stdout, stderr)
+
+	// Registered even on failure, so the exit code can be inspected
+	if a.Register != "" {
+		runtime.Env[a.Register] = strings.TrimRight(capturedOut.String(), "\n")
+		runtime.Env[a.Register+"_STDERR"] = strings.TrimRight(capturedErr.String(), "\n")
+		runtime.Env[a.Register+"_RC"] = strconv.Itoa(ssh.ExitCode(err))
+	}
+
+	if err != nil {
 		return fmt.Errorf("command execution failed: %w", err)
 	}
 
 	return nil
 }
 
+// env returns the job's env with the action's env (expanded against it) on top
+func (a *RunAction) env(runtime *types.Runtime) map[string]string {
+	if len(a.Env) == 0 {
+		return runtime.Env
+	}
+
+	env := maps.Clone(runtime.Env)
+	if env == nil {
+		env = make(map[string]string)
+	}
+	for name, value := range a.Env {
+		env[name] = ExpandEnvVars(value, runtime.Env)
+	}
+	return env
+}
+
+// command returns the command to send, wrapped in the shell and cwd
+func (a *RunAction) command(env map[string]string) string {
+	// Expand environment variables in the command
+	cmd := ExpandEnvVars(a.Command, env)
+
+	if a.Shell != "" {
+		cmd = a.Shell + " -c " + ssh.Quote(cmd)
+	}
+	if a.Cwd != "" {
+		// A failed cd must not run the command in the wrong directory
+		cmd = "cd -- " + ssh.Quote(ExpandEnvVars(a.Cwd, env)) + " || exit\n" + cmd
+	}
+	return cmd
+}
+
+// openStdin opens the local file or artifact fed to the command, if any
+func (a *RunAction) openStdin(runtime *types.Runtime, env map[string]string) (io.ReadCloser, error) {
+	if a.Stdin == nil {
+		return nil, nil
+	}
+
+	if a.Stdin.Artifact != "" {
+		reader, err := runtime.ArtifactMgr.Get(a.Stdin.Artifact)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get artifact %s: %w", a.Stdin.Artifact, err)
+		}
+		return reader, nil
+	}
+
+	src := ExpandEnvVars(a.Stdin.Src, env)
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin file %s: %w", src, err)
+	}
+	return f, nil
+}
+
 func (a *RunAction) DryRun(ctx context.Context, runtime *types.Runtime) string {
 	// Expand environment variables for dry-run display
-	cmd := ExpandEnvVars(a.Command, runtime.Env)
-	return fmt.Sprintf("run: %s", cmd)
+	env := a.env(runtime)
+	desc := fmt.Sprintf("run: %s", strings.TrimRight(ExpandEnvVars(a.Command, env), "\n"))
+
+	var options []string
+	if a.Cwd != "" {
+		options = append(options, "cwd: "+ExpandEnvVars(a.Cwd, env))
+	}
+	if a.Shell != "" {
+		options = append(options, "shell: "+a.Shell)
+	}
+	if a.User != "" {
+		options = append(options, "user: "+ExpandEnvVars(a.User, env))
+	}
+	if a.Register != "" {
+		options = append(options, "register: "+a.Register)
+	}
+	if a.Stdin != nil {
+		if a.Stdin.Artifact != "" {
+			options = append(options, "stdin: artifact:"+a.Stdin.Artifact)
+		} else {
+			options = append(options, "stdin: "+ExpandEnvVars(a.Stdin.Src, env))
+		}
+	}
+	if len(options) > 0 {
+		desc += fmt.Sprintf(" (%s)", strings.Join(options, ", "))
+	}
+	return desc
 }
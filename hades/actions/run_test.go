@@ -0,0 +1,89 @@
+package actions
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/artifacts"
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+func TestRunAction_Options(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, []byte("from file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		run     schema.ActionRun
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", run: schema.ActionRun{Cmd: "echo hello"}, want: "hello\n"},
+		{name: "cwd", run: schema.ActionRun{Cmd: "pwd", Cwd: dir}, want: dir + "\n"},
+		{name: "shell", run: schema.ActionRun{Cmd: "false | true", Shell: "bash -euo pipefail"}, wantErr: true},
+		{name: "env", run: schema.ActionRun{Cmd: `printf %s "$GREETING"`, Env: map[string]string{"GREETING": "hi ${NAME}"}}, want: "hi web"},
+		{name: "env in cmd", run: schema.ActionRun{Cmd: "printf %s ${GREETING}", Env: map[string]string{"GREETING": "hi"}}, want: "hi"},
+		{name: "stdin file", run: schema.ActionRun{Cmd: "cat", Stdin: &schema.RunStdin{Src: input}}, want: "from file"},
+		{name: "stdin artifact", run: schema.ActionRun{Cmd: "cat", Stdin: &schema.RunStdin{Artifact: "payload"}}, want: "from artifact"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			runtime := newLocalRuntime(map[string]string{"NAME": "web"})
+			runtime.Stdout = &stdout
+			runtime.ArtifactMgr = artifacts.NewManager()
+			runtime.ArtifactMgr.Store("payload", bytes.NewReader([]byte("from artifact")))
+			defer runtime.ArtifactMgr.Clear()
+
+			err := NewRunAction(&tt.run, "").Execute(context.Background(), runtime)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := stdout.String(); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRunAction_CwdMissing(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+	action := NewRunAction(&schema.ActionRun{Cmd: "touch " + marker, Cwd: filepath.Join(dir, "missing")}, "")
+
+	if err := action.Execute(context.Background(), newLocalRuntime(nil)); err == nil {
+		t.Error("Expected error for missing cwd")
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Expected command not to run after failed cd")
+	}
+}
+
+func TestRunAction_RegisterFailure(t *testing.T) {
+	runtime := newLocalRuntime(map[string]string{})
+	action := NewRunAction(&schema.ActionRun{Cmd: "echo partial; echo broken >&2; exit 3"}, "RESULT")
+
+	if err := action.Execute(context.Background(), runtime); err == nil {
+		t.Fatal("Expected error from failing command")
+	}
+
+	want := map[string]string{"RESULT": "partial", "RESULT_STDERR": "broken", "RESULT_RC": "3"}
+	for name, value := range want {
+		if got := runtime.Env[name]; got != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, got)
+		}
+	}
+}
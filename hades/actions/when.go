@@ -0,0 +1,44 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/selector"
+	"github.com/SoftKiwiGames/hades/hades/types"
+)
+
+// EvaluateWhen reports whether an action with the given condition should run
+func EvaluateWhen(ctx context.Context, when *schema.When, runtime *types.Runtime) (bool, error) {
+	if when == nil {
+		return true, nil // No condition = always run
+	}
+
+	if when.Expr != "" {
+		pass, errs := selector.Eval(when.Expr, runtime.Env)
+		if errs != nil {
+			return false, fmt.Errorf("failed to evaluate when expression: %w", errs)
+		}
+		return pass, nil
+	}
+
+	// Shell test, evaluated the same way as a job guard
+	result, err := EvaluateGuard(ctx, &schema.Guard{If: when.If}, runtime)
+	if err != nil {
+		return false, fmt.Errorf("when evaluation failed: %w", err)
+	}
+	return result.Pass, nil
+}
+
+// FormatWhenCondition returns human-readable condition description
+func FormatWhenCondition(when *schema.When, env map[string]string) string {
+	if when == nil {
+		return ""
+	}
+
+	if when.Expr != "" {
+		return fmt.Sprintf("when: %s", when.Expr)
+	}
+	return fmt.Sprintf("when: %s", ExpandEnvVars(when.If, env))
+}
@@ -1,7 +1,6 @@
 package artifacts
 
 import (
-	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"io"
@@ -14,6 +13,7 @@ type Manager interface {
 	Register(name string, path string)
 	Get(name string) (io.ReadCloser, error)
 	Checksum(name string) (string, error)
+	Size(name string) (int64, error)
 	List() []string
 	Clear()
 }
@@ -23,10 +23,13 @@ type manager struct {
 	artifacts map[string]*artifact
 }
 
+// artifact is backed by a file on disk and streamed on every Get,
+// so large artifacts are never held in memory
 type artifact struct {
-	data     []byte
+	mu       sync.Mutex // Guards checksum (computed once, on first use)
+	path     string
 	checksum string
-	path     string // lazy-load: file read on first access
+	temp     bool // path is a spool file owned by the manager
 }
 
 func NewManager() Manager {
@@ -39,88 +42,110 @@ func (m *manager) Register(name string, path string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.artifacts[name] = &artifact{path: path}
+	m.replace(name, &artifact{path: path})
 }
 
-func (m *manager) load(art *artifact) error {
-	if art.data != nil {
-		return nil
+// replace sets the artifact for name, removing a spool file it supersedes
+func (m *manager) replace(name string, art *artifact) {
+	if old, ok := m.artifacts[name]; ok && old.temp {
+		os.Remove(old.path)
 	}
-	if art.path == "" {
-		return fmt.Errorf("artifact has no path and no data")
-	}
-	f, err := os.Open(art.path)
+	m.artifacts[name] = art
+}
+
+// Store spools data to a temp file; it is removed by Clear
+func (m *manager) Store(name string, data io.Reader) error {
+	f, err := os.CreateTemp("", "hades-artifact-*")
 	if err != nil {
-		return fmt.Errorf("failed to open artifact at %s: %w", art.path, err)
+		return fmt.Errorf("failed to create artifact file: %w", err)
 	}
 	defer f.Close()
 
-	buf, err := io.ReadAll(f)
-	if err != nil {
-		return fmt.Errorf("failed to read artifact at %s: %w", art.path, err)
+	// Checksum while spooling so the data is only read once
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hash), data); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to read artifact data: %w", err)
 	}
 
-	hash := sha256.Sum256(buf)
-	art.data = buf
-	art.checksum = fmt.Sprintf("%x", hash)
-	return nil
-}
-
-func (m *manager) Store(name string, data io.Reader) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Read all data
-	buf, err := io.ReadAll(data)
-	if err != nil {
-		return fmt.Errorf("failed to read artifact data: %w", err)
-	}
-
-	// Calculate checksum
-	hash := sha256.Sum256(buf)
-	checksum := fmt.Sprintf("%x", hash)
-
-	m.artifacts[name] = &artifact{
-		data:     buf,
-		checksum: checksum,
-	}
+	m.replace(name, &artifact{
+		path:     f.Name(),
+		checksum: fmt.Sprintf("%x", hash.Sum(nil)),
+		temp:     true,
+	})
 
 	return nil
 }
 
-func (m *manager) Get(name string) (io.ReadCloser, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (m *manager) lookup(name string) (*artifact, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
 	art, ok := m.artifacts[name]
 	if !ok {
 		return nil, fmt.Errorf("artifact %q not found", name)
 	}
+	return art, nil
+}
 
-	if err := m.load(art); err != nil {
+func (m *manager) Get(name string) (io.ReadCloser, error) {
+	art, err := m.lookup(name)
+	if err != nil {
 		return nil, err
 	}
 
 	// Return a new reader each time
-	return io.NopCloser(bytes.NewReader(art.data)), nil
+	f, err := os.Open(art.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artifact at %s: %w", art.path, err)
+	}
+	return f, nil
 }
 
 func (m *manager) Checksum(name string) (string, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	art, err := m.lookup(name)
+	if err != nil {
+		return "", err
+	}
 
-	art, ok := m.artifacts[name]
-	if !ok {
-		return "", fmt.Errorf("artifact %q not found", name)
+	art.mu.Lock()
+	defer art.mu.Unlock()
+
+	if art.checksum != "" {
+		return art.checksum, nil
 	}
 
-	if err := m.load(art); err != nil {
-		return "", err
+	f, err := os.Open(art.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open artifact at %s: %w", art.path, err)
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("failed to read artifact at %s: %w", art.path, err)
 	}
 
+	art.checksum = fmt.Sprintf("%x", hash.Sum(nil))
 	return art.checksum, nil
 }
 
+func (m *manager) Size(name string) (int64, error) {
+	art, err := m.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+
+	stat, err := os.Stat(art.path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat artifact at %s: %w", art.path, err)
+	}
+	return stat.Size(), nil
+}
+
 func (m *manager) List() []string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -136,5 +161,10 @@ func (m *manager) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	for _, art := range m.artifacts {
+		if art.temp {
+			os.Remove(art.path)
+		}
+	}
 	m.artifacts = make(map[string]*artifact)
 }
@@ -5,12 +5,15 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/SoftKiwiGames/hades/hades/executor"
 	"github.com/SoftKiwiGames/hades/hades/inventory"
 	"github.com/SoftKiwiGames/hades/hades/loader"
 	"github.com/SoftKiwiGames/hades/hades/ssh"
+	"github.com/SoftKiwiGames/hades/hades/state"
 	"github.com/spf13/cobra"
 	"github.com/wzshiming/ctc"
 )
@@ -54,6 +57,9 @@ func (h *Hades) buildRunCommand() *cobra.Command {
 		targets   []string
 		envVars   []string
 		dryRun    bool
+		resume    string
+		startAt   string
+		onlySteps []string
 	)
 
 	cmd := &cobra.Command{
@@ -64,7 +70,7 @@ func (h *Hades) buildRunCommand() *cobra.Command {
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			planName := args[0]
-			return h.runPlan(planName, configDir, targets, envVars, dryRun)
+			return h.runPlan(planName, configDir, targets, envVars, dryRun, resume, startAt, onlySteps)
 		},
 	}
 
@@ -72,11 +78,17 @@ func (h *Hades) buildRunCommand() *cobra.Command {
 	cmd.Flags().StringSliceVarP(&targets, "target", "t", nil, "Target groups to execute on")
 	cmd.Flags().StringSliceVarP(&envVars, "env", "e", nil, "Environment variables (KEY=VALUE)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be executed without running")
+	cmd.Flags().StringVar(&resume, "resume", "", "Continue a failed or interrupted run (run ID) with its env and hosts")
+	cmd.Flags().StringVar(&startAt, "start-at-step", "", "Skip the steps before this one")
+	cmd.Flags().StringSliceVar(&onlySteps, "only-steps", nil, "Run only these steps")
+	cmd.MarkFlagsMutuallyExclusive("start-at-step", "only-steps")
+	cmd.MarkFlagsMutuallyExclusive("resume", "env")
+	cmd.MarkFlagsMutuallyExclusive("resume", "target")
 
 	return cmd
 }
 
-func (h *Hades) runPlan(planName, configDir string, targets, envVars []string, dryRun bool) error {
+func (h *Hades) runPlan(planName, configDir string, targets, envVars []string, dryRun bool, resume, startAt string, onlySteps []string) error {
 	// Load and merge all YAML files from the config directory
 	file, err := h.loader.LoadDirectory(configDir)
 	if err != nil {
@@ -100,12 +112,46 @@ func (h *Hades) runPlan(planName, configDir string, targets, envVars []string, d
 		return fmt.Errorf("failed to parse environment variables: %w", err)
 	}
 
-	// Expand environment variables (${VAR})
+	// Resuming reuses the run's env and targets
+	var runState *state.State
+	if resume != "" {
+		runState, err = state.Load(resume)
+		if err != nil {
+			return fmt.Errorf("failed to resume: %w", err)
+		}
+		if runState.Plan != planName {
+			return fmt.Errorf("failed to resume: run %s is of plan %q", resume, runState.Plan)
+		}
+		if runState.Status == state.StatusCompleted {
+			return fmt.Errorf("failed to resume: run %s already completed", resume)
+		}
+		targets = runState.Targets
+		env = runState.Env
+	}
+
+	// Expand environment variables (${VAR}); the state keeps them unexpanded
 	expandedEnv, err := h.loader.ExpandEnv(env)
 	if err != nil {
 		return fmt.Errorf("failed to expand environment variables: %w", err)
 	}
 
+	// Select the steps to run; a resumed run runs the steps it has not completed
+	if resume != "" && startAt == "" && len(onlySteps) == 0 {
+		for _, step := range plan.Steps {
+			if stepState := runState.Step(step.Name); stepState == nil || stepState.Status != state.StatusCompleted {
+				onlySteps = append(onlySteps, step.Name)
+			}
+		}
+		if len(onlySteps) == 0 {
+			return fmt.Errorf("failed to resume: every step of run %s completed", resume)
+		}
+	}
+	steps, err := loader.SelectSteps(plan, startAt, onlySteps)
+	if err != nil {
+		return fmt.Errorf("failed to select steps: %w", err)
+	}
+	opts := executor.RunOptions{Steps: steps, Resume: runState, RawEnv: env}
+
 	// Validate environment variables against plan
 	if err := loader.ValidatePlanEnv(file, planName, expandedEnv); err != nil {
 		return fmt.Errorf("environment validation failed: %w", err)
@@ -132,23 +178,64 @@ func (h *Hades) runPlan(planName, configDir string, targets, envVars []string, d
 	exec := executor.New(sshClient, h.stdout, h.stderr)
 
 	// Execute plan or dry-run
-	ctx := context.Background()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	if dryRun {
-		return exec.DryRun(ctx, file, plan, planName, inv, targets, expandedEnv)
+		return exec.DryRun(ctx, file, plan, planName, inv, targets, expandedEnv, opts)
 	}
 
-	result, err := exec.ExecutePlan(ctx, file, plan, planName, inv, targets, expandedEnv)
+	stopSignals := h.handleSignals(exec, cancel)
+	defer stopSignals()
+
+	result, err := exec.ExecutePlan(ctx, file, plan, planName, inv, targets, expandedEnv, opts)
+	if result != nil && result.Interrupted {
+		return fmt.Errorf("plan interrupted at step %q (continue with --resume %s)", result.InterruptedStep, result.RunID)
+	}
+	if result != nil && result.Failed && result.RunID != "" {
+		return fmt.Errorf("execution failed: %w (continue with --resume %s)", err, result.RunID)
+	}
 	if err != nil {
 		return fmt.Errorf("execution failed: %w", err)
 	}
 
 	if result.Failed {
-		return fmt.Errorf("plan failed")
+		return fmt.Errorf("plan failed (continue with --resume %s)", result.RunID)
 	}
 
 	return nil
 }
 
+// handleSignals makes the first SIGINT/SIGTERM stop scheduling new work and
+// the second cancel running actions. The returned func stops listening.
+func (h *Hades) handleSignals(exec executor.Executor, cancel context.CancelFunc) func() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		fmt.Fprintf(h.stderr, "\n%sInterrupted:%s waiting for running actions to finish (press Ctrl-C again to force)\n", ctc.ForegroundYellow, ctc.Reset)
+		exec.Interrupt()
+
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		fmt.Fprintf(h.stderr, "\n%sInterrupted:%s cancelling running actions\n", ctc.ForegroundRed, ctc.Reset)
+		cancel()
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
 func (h *Hades) confirmDynamicHosts(hosts []ssh.Host) error {
 	fmt.Fprintf(h.stdout, "\n%sDynamic inventory detected %d host(s):%s\n\n", ctc.ForegroundYellow, len(hosts), ctc.Reset)
 
@@ -161,7 +248,11 @@ func (h *Hades) confirmDynamicHosts(hosts []ssh.Host) error {
 
 	fmt.Fprintf(h.stdout, "\033[1m  %-*s  %s\033[0m\n", nameW, "NAME", "ADDRESS")
 	for _, host := range hosts {
-		fmt.Fprintf(h.stdout, "  %-*s  %s\n", nameW, host.Name, host.Address)
+		address := host.Address
+		if host.Jump != nil {
+			address = fmt.Sprintf("%s (via %s)", host.Address, host.Jump.Name)
+		}
+		fmt.Fprintf(h.stdout, "  %-*s  %s\n", nameW, host.Name, address)
 	}
 
 	fmt.Fprintf(h.stdout, "\nProceed? (yes/no): ")
@@ -62,6 +62,9 @@ func AWSInstances(ctx context.Context, cfg AWSConfig) ([]CloudInstance, error) {
 				if inst.PublicIpAddress != nil {
 					ci.PublicIPv4 = net.ParseIP(*inst.PublicIpAddress)
 				}
+				if inst.PrivateIpAddress != nil {
+					ci.PrivateIP = net.ParseIP(*inst.PrivateIpAddress)
+				}
 				if len(inst.NetworkInterfaces) > 0 {
 					for _, addr := range inst.NetworkInterfaces[0].Ipv6Addresses {
 						if addr.Ipv6Address != nil {
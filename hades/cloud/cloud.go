@@ -6,5 +6,6 @@ type CloudInstance struct {
 	Name       string
 	PublicIPv4 net.IP
 	PublicIPv6 net.IP
+	PrivateIP  net.IP
 	Tags       map[string]string
 }
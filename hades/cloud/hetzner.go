@@ -36,6 +36,9 @@ func HetznerInstances(ctx context.Context, cfg HetznerConfig) ([]CloudInstance,
 		if !s.PublicNet.IPv6.IP.IsUnspecified() {
 			inst.PublicIPv6 = s.PublicNet.IPv6.IP
 		}
+		if len(s.PrivateNet) > 0 {
+			inst.PrivateIP = s.PrivateNet[0].IP
+		}
 
 		instances = append(instances, inst)
 	}
@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/SoftKiwiGames/hades/hades/loader"
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+)
+
+// betweenBatches pauses and asks for confirmation before the given batch
+// (1-based), as the step requests
+func (e *executor) betweenBatches(ctx context.Context, out *stepOutput, step schema.Step, pause time.Duration, batch, total int) error {
+	if pause > 0 {
+		fmt.Fprintf(out.stdout, "  Pausing %s before batch %d/%d\n", pause, batch, total)
+		if !e.sleep(ctx, pause) {
+			return fmt.Errorf("%w before batch %d/%d", ErrInterrupted, batch, total)
+		}
+	}
+
+	if step.ConfirmBetweenBatches {
+		approved, err := e.confirm(ctx, out.stdout, fmt.Sprintf("Continue with batch %d/%d?", batch, total))
+		if err != nil {
+			return fmt.Errorf("%w before batch %d/%d", err, batch, total)
+		}
+		if !approved {
+			return fmt.Errorf("user declined to continue with batch %d/%d", batch, total)
+		}
+	}
+
+	return nil
+}
+
+// confirm asks a yes/no question on stdin, printing it to w. Interrupt stops
+// waiting for the answer. Concurrent steps take turns, and every answer goes
+// to the prompt it was typed for.
+func (e *executor) confirm(ctx context.Context, w io.Writer, question string) (bool, error) {
+	e.promptMu.Lock()
+	defer e.promptMu.Unlock()
+
+	// A read left over from an abandoned prompt answers this one
+	if !e.reading {
+		e.reading = true
+		go e.readAnswer()
+	}
+
+	fmt.Fprintf(w, "\n⏸️  %s [y/N]: ", question)
+
+	select {
+	case answer := <-e.answers:
+		e.reading = false
+		fmt.Fprintln(w)
+		answer = strings.TrimSpace(strings.ToLower(answer))
+		return answer == "y" || answer == "yes", nil
+	case <-e.stop:
+		return false, ErrInterrupted
+	case <-ctx.Done():
+		return false, ErrInterrupted
+	}
+}
+
+// readAnswer reads one line of stdin for a prompt. Nothing reads stdin between
+// prompts, so password prompts on the terminal get what the user types. Once
+// stdin is closed, every prompt gets an empty answer.
+func (e *executor) readAnswer() {
+	answer, _ := e.stdin.ReadString('\n')
+	e.answers <- answer
+}
+
+// checkBatch runs the step's batch check on hosts that finished a batch.
+// Hosts failing the check count as failed hosts of the step.
+func (e *executor) checkBatch(ctx context.Context, run *planRun, out *stepOutput, step schema.Step, targetName string, hosts []ssh.Host, env map[string]string) ([]hostFailure, error) {
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+
+	job, name := loader.BatchCheckJob(run.file, step.BatchCheck)
+	e.loadArtifacts(run.file, job, run.artifactMgr)
+	fmt.Fprintf(out.stdout, "  Checking batch (%s)\n", formatBatchCheck(step.BatchCheck))
+
+	failures, err := e.executeBatch(ctx, run.file, job, name, run.result.RunID, run.planName, targetName, hosts, loader.MergeEnv(job, env), run.artifactMgr, run.registryMgr, run.vars)
+	for i := range failures {
+		failures[i].err = fmt.Errorf("batch check failed: %w", failures[i].err)
+	}
+	return failures, err
+}
+
+// formatBatchCheck describes a batch check for the console and dry-run
+func formatBatchCheck(check *schema.BatchCheck) string {
+	if check.Job != "" {
+		return "job: " + check.Job
+	}
+	if check.Local {
+		return "local: " + check.Run
+	}
+	return "run: " + check.Run
+}
+
+// finished returns the hosts of a batch that did not fail
+func finished(batch []ssh.Host, failures []hostFailure) []ssh.Host {
+	failed := make(map[string]bool)
+	for _, failure := range failures {
+		failed[failure.host] = true
+	}
+
+	var hosts []ssh.Host
+	for _, host := range batch {
+		if !failed[host.Name] {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,104 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SoftKiwiGames/hades/hades/loader"
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+)
+
+// cleanupStep is a rollback, on_failure or always step with the hosts it
+// runs on when it has no targets of its own
+type cleanupStep struct {
+	step  schema.Step
+	hosts []ssh.Host
+}
+
+// rollbackSteps returns the rollback jobs of the steps that ran, newest
+// first, each on the hosts its step was started on
+func rollbackSteps(steps []schema.Step, touched [][]ssh.Host) []cleanupStep {
+	var rollbacks []cleanupStep
+	for i := len(touched) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.Rollback == "" || len(touched[i]) == 0 {
+			continue
+		}
+		rollbacks = append(rollbacks, cleanupStep{step: loader.RollbackStep(step), hosts: touched[i]})
+	}
+	return rollbacks
+}
+
+// cleanupSteps pairs on_failure or always steps with every host the plan touched
+func cleanupSteps(steps []schema.Step, touched [][]ssh.Host) []cleanupStep {
+	var hosts []ssh.Host
+	seen := make(map[string]bool)
+	for _, stepHosts := range touched {
+		for _, host := range stepHosts {
+			if !seen[host.Name] {
+				seen[host.Name] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+
+	cleanup := make([]cleanupStep, len(steps))
+	for i, step := range steps {
+		cleanup[i] = cleanupStep{step: step, hosts: hosts}
+	}
+	return cleanup
+}
+
+// runCleanup runs every step even if one fails, including hosts that failed
+// earlier. Returns the first failure, or the interruption that stopped it.
+func (e *executor) runCleanup(ctx context.Context, run *planRun, title string, steps []cleanupStep, env map[string]string) error {
+	if len(steps) == 0 {
+		return nil
+	}
+	e.ui.Section(title)
+
+	var firstErr error
+	for i, cleanup := range steps {
+		step := cleanup.step
+		if e.stopping(ctx) {
+			return fmt.Errorf("%w before %s step %q", ErrInterrupted, title, step.Name)
+		}
+
+		// Steps with targets run on those, otherwise on the hosts the plan touched
+		hosts := cleanup.hosts
+		targetName := ""
+		if len(step.Targets) > 0 {
+			// Determine which targets to use: CLI overrides YAML
+			stepTargets := step.Targets
+			if len(run.targets) > 0 {
+				stepTargets = run.targets
+			}
+
+			resolved, err := e.resolveHosts(run, step, stepTargets, false)
+			if err == nil {
+				resolved, err = limitHosts(step, resolved)
+			}
+			if err != nil {
+				e.ui.Error("%s step %q: %v", title, step.Name, err)
+				if firstErr == nil {
+					firstErr = &stepFailure{step: step.Name, err: err}
+				}
+				continue
+			}
+			hosts = resolved
+			targetName = stepTargets[0]
+		}
+
+		e.ui.StepProgress(i+1, len(steps), step.Name)
+		_, err := e.executeStep(ctx, run, e.stepOutput(step.Name, false), step, hosts, targetName, env, nil)
+		if errors.Is(err, ErrInterrupted) {
+			return err
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -1,9 +1,17 @@
 package executor
 
 import (
+	"bufio"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -17,14 +25,18 @@ import (
 	"github.com/SoftKiwiGames/hades/hades/rollout"
 	"github.com/SoftKiwiGames/hades/hades/schema"
 	"github.com/SoftKiwiGames/hades/hades/ssh"
+	"github.com/SoftKiwiGames/hades/hades/state"
 	"github.com/SoftKiwiGames/hades/hades/types"
 	"github.com/SoftKiwiGames/hades/hades/ui"
 	"github.com/wzshiming/ctc"
 )
 
 type Executor interface {
-	ExecutePlan(ctx context.Context, file *schema.File, plan *schema.Plan, planName string, inv inventory.Inventory, targets []string, env map[string]string) (*Result, error)
-	DryRun(ctx context.Context, file *schema.File, plan *schema.Plan, planName string, inv inventory.Inventory, targets []string, env map[string]string) error
+	ExecutePlan(ctx context.Context, file *schema.File, plan *schema.Plan, planName string, inv inventory.Inventory, targets []string, env map[string]string, opts RunOptions) (*Result, error)
+	DryRun(ctx context.Context, file *schema.File, plan *schema.Plan, planName string, inv inventory.Inventory, targets []string, env map[string]string, opts RunOptions) error
+	// Interrupt stops scheduling new batches and actions; running actions finish.
+	// Cancel the ExecutePlan context to stop running actions as well.
+	Interrupt()
 }
 
 type Result struct {
@@ -35,6 +47,47 @@ type Result struct {
 	FailedStep string
 	FailedHost string
 	Error      error
+
+	FailedHosts []string // Hosts whose job failed, including failures tolerated by max_fail
+
+	Interrupted      bool     // Run was stopped by Interrupt or context cancellation
+	InterruptedStep  string   // Step that was running (or next to run) when interrupted
+	InterruptedHosts []string // Hosts whose job was cut short
+}
+
+// RunOptions selects the plan steps to run and the earlier run they continue
+type RunOptions struct {
+	Steps  []string     // Steps to run (all when empty)
+	Resume *state.State // Run to continue: its run ID, hosts and completed hosts are reused
+
+	// CLI env before ${VAR} expansion, saved for --resume so expanded secrets
+	// aren't written to disk. Defaults to the env passed to ExecutePlan.
+	RawEnv map[string]string
+}
+
+// ErrInterrupted is returned when a run is stopped before it completes
+var ErrInterrupted = errors.New("interrupted")
+
+// interruptedError records the hosts whose jobs an interrupt cut short
+type interruptedError struct {
+	hosts []string
+}
+
+func (e *interruptedError) Error() string {
+	return fmt.Sprintf("interrupted on %s", strings.Join(e.hosts, ", "))
+}
+
+func (e *interruptedError) Unwrap() error {
+	return ErrInterrupted
+}
+
+// TimeoutError is returned when an action runs longer than its timeout
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.Timeout)
 }
 
 type executor struct {
@@ -42,6 +95,14 @@ type executor struct {
 	stdout    io.Writer
 	stderr    io.Writer
 	ui        *ui.Output
+	stdin     *bufio.Reader // Answers to confirm_between_batches
+
+	answers  chan string // The line read for the current prompt
+	reading  bool        // A line is being read; guarded by promptMu
+	promptMu sync.Mutex  // One confirmation prompt at a time, across concurrent steps
+
+	stop     chan struct{} // Closed by Interrupt
+	stopOnce sync.Once
 }
 
 func New(sshClient ssh.Client, stdout, stderr io.Writer) Executor {
@@ -50,10 +111,58 @@ func New(sshClient ssh.Client, stdout, stderr io.Writer) Executor {
 		stdout:    stdout,
 		stderr:    stderr,
 		ui:        ui.NewOutput(stdout, stderr),
+		stdin:     bufio.NewReader(os.Stdin),
+		answers:   make(chan string, 1),
+		stop:      make(chan struct{}),
+	}
+}
+
+func (e *executor) Interrupt() {
+	e.stopOnce.Do(func() {
+		close(e.stop)
+	})
+}
+
+// stopping reports whether Interrupt was called or ctx was cancelled
+func (e *executor) stopping(ctx context.Context) bool {
+	select {
+	case <-e.stop:
+		return true
+	default:
+		return ctx.Err() != nil
+	}
+}
+
+// sleep waits for d, returning false if interrupted or cancelled first
+func (e *executor) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-e.stop:
+		return false
+	case <-ctx.Done():
+		return false
 	}
 }
 
-func (e *executor) ExecutePlan(ctx context.Context, file *schema.File, plan *schema.Plan, planName string, inv inventory.Inventory, targets []string, env map[string]string) (*Result, error) {
+// interrupted records an interrupted run in result
+func (e *executor) interrupted(result *Result, step string, err error) (*Result, error) {
+	result.Interrupted = true
+	result.InterruptedStep = step
+	var ie *interruptedError
+	if errors.As(err, &ie) {
+		result.InterruptedHosts = ie.hosts
+	}
+	result.Error = err
+	result.EndTime = time.Now()
+	e.ui.PlanInterrupted(step, result.InterruptedHosts)
+	return result, err
+}
+
+func (e *executor) ExecutePlan(ctx context.Context, file *schema.File, plan *schema.Plan, planName string, inv inventory.Inventory, targets []string, env map[string]string, opts RunOptions) (*Result, error) {
 	result := &Result{
 		StartTime: time.Now(),
 	}
@@ -70,138 +179,443 @@ func (e *executor) ExecutePlan(ctx context.Context, file *schema.File, plan *sch
 		return result, result.Error
 	}
 
-	// Generate unique run ID
-	result.RunID = "hades-" + time.Now().Format("20060102-150405")
+	// Generate unique run ID, or continue the resumed run
+	st := opts.Resume
+	if st != nil {
+		result.RunID = st.RunID
+		st.Status = state.StatusRunning
+	} else {
+		result.RunID = newRunID()
+		var names []string
+		for _, step := range plan.Steps {
+			names = append(names, step.Name)
+		}
+		savedEnv := opts.RawEnv
+		if savedEnv == nil {
+			savedEnv = env
+		}
+		st = state.New(result.RunID, planName, targets, savedEnv, names)
+	}
 
-	e.ui.PlanStarted(planName, result.RunID)
+	run := &planRun{
+		file:        file,
+		plan:        plan,
+		planName:    planName,
+		inv:         inv,
+		targets:     targets,
+		env:         env,
+		result:      result,
+		artifactMgr: artifactMgr,
+		registryMgr: registryMgr,
+		vars:        newHostVars(st.Vars),
+		failedHosts: make(map[string]bool),
+		state:       st,
+	}
 
-	// Execute each step sequentially
-	for i, step := range plan.Steps {
-		// Determine which targets to use: CLI overrides YAML
-		stepTargets := step.Targets
-		if len(targets) > 0 {
-			stepTargets = targets
+	// Steps added to the plan since the resumed run started
+	for _, step := range plan.Steps {
+		if st.Step(step.Name) == nil {
+			st.Steps = append(st.Steps, state.Step{Name: step.Name, Status: state.StatusPending})
 		}
+	}
 
-		// Resolve all targets and deduplicate hosts
-		uniqueHosts := make(map[string]ssh.Host) // keyed by host name
-		for _, targetName := range stepTargets {
-			hosts, err := inv.ResolveTarget(targetName)
-			if err != nil {
-				result.Failed = true
-				result.FailedStep = step.Name
-				result.Error = fmt.Errorf("failed to resolve target %q: %w", targetName, err)
-				return result, result.Error
-			}
-			// Add hosts to unique set
-			for _, host := range hosts {
-				uniqueHosts[host.Name] = host
+	// Hosts that failed a completed step stay excluded when resuming
+	for _, step := range st.Steps {
+		if step.Status == state.StatusCompleted {
+			for _, host := range step.Failed {
+				run.failedHosts[host] = true
 			}
 		}
+	}
+
+	e.ui.PlanStarted(planName, result.RunID)
+	e.warnSkippedRegisters(run, opts)
+	e.saveState(run)
 
-		// Convert map to slice
-		var allHosts []ssh.Host
-		for _, host := range uniqueHosts {
-			allHosts = append(allHosts, host)
+	// Hosts each step ran on, for rollback and cleanup
+	runSteps := e.runSteps
+	if plan.Strategy == schema.StrategyPerHost {
+		runSteps = e.runPerHost
+	}
+	touched, stoppedAt, err := runSteps(ctx, run, opts)
+	if errors.Is(err, ErrInterrupted) {
+		st.Status = state.StatusInterrupted
+		e.saveState(run)
+		return e.interrupted(result, stoppedAt, err)
+	}
+	if failure := asStepFailure(err, stoppedAt); failure != nil {
+		result.Failed = true
+		result.FailedStep = failure.step
+		result.FailedHost = failure.host
+		result.Error = failure.err
+		st.Status = state.StatusFailed
+		e.saveState(run)
+
+		// Undo and clean up; their own failures are reported but don't replace the original error
+		failureEnv := map[string]string{
+			"HADES_FAILED_STEP":  failure.step,
+			"HADES_FAILED_HOST":  failure.host,
+			"HADES_FAILED_ERROR": failure.err.Error(),
 		}
+		e.runCleanup(ctx, run, "Rollback", rollbackSteps(plan.Steps, touched), failureEnv)
+		e.runCleanup(ctx, run, "On failure", cleanupSteps(plan.OnFailure, touched), failureEnv)
+		e.runCleanup(ctx, run, "Always", cleanupSteps(plan.Always, touched), failureEnv)
 
-		// Apply limit if specified (canary)
-		if step.Limit > 0 && step.Limit < len(allHosts) {
-			allHosts = allHosts[:step.Limit]
+		result.EndTime = time.Now()
+		return result, result.Error
+	}
+
+	// Cleanup after success sees empty HADES_FAILED_* variables
+	successEnv := map[string]string{
+		"HADES_FAILED_STEP":  "",
+		"HADES_FAILED_HOST":  "",
+		"HADES_FAILED_ERROR": "",
+	}
+	if err := e.runCleanup(ctx, run, "Always", cleanupSteps(plan.Always, touched), successEnv); err != nil {
+		if errors.Is(err, ErrInterrupted) {
+			st.Status = state.StatusInterrupted
+			e.saveState(run)
+			return e.interrupted(result, "", err)
 		}
+		failure := asStepFailure(err, "")
+		st.Status = state.StatusFailed
+		e.saveState(run)
+		result.Failed = true
+		result.FailedStep = failure.step
+		result.FailedHost = failure.host
+		result.Error = failure.err
+		result.EndTime = time.Now()
+		return result, result.Error
+	}
 
-		totalHosts := len(allHosts)
+	st.Status = state.StatusCompleted
+	e.saveState(run)
 
-		e.ui.StepProgress(i+1, len(plan.Steps), step.Name)
-		e.ui.Info("  Job: %s", step.Job)
-		targetsStr := strings.Join(stepTargets, ", ")
-		e.ui.Info("  Targets: %s", targetsStr)
-		fmt.Fprintf(e.stdout, "  Hosts: %d\n", totalHosts)
-		fmt.Fprintf(e.stdout, "  Status: %s□%s Started\n", ctc.ForegroundYellow, ctc.Reset)
-		fmt.Fprintf(e.stdout, "  Started: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	result.EndTime = time.Now()
+	e.ui.PlanCompleted(result.EndTime.Sub(result.StartTime))
+	if len(result.FailedHosts) > 0 {
+		e.ui.Warning("Failed hosts: %s", strings.Join(result.FailedHosts, ", "))
+	}
 
-		// Load job once for this step
-		job, err := e.loadJob(file, step.Job)
-		if err != nil {
-			result.Failed = true
-			result.FailedStep = step.Name
-			result.Error = err
-			return result, result.Error
-		}
+	return result, nil
+}
 
-		// Merge env with priority: CLI > step > plan > job defaults
-		stepEnv := make(map[string]string)
+// newRunID returns an ID that sorts by start time; the random suffix keeps
+// runs started in the same second apart
+func newRunID() string {
+	var random [3]byte
+	rand.Read(random[:])
+	return "hades-" + time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(random[:])
+}
 
-		// Start with plan-level env
-		for k, v := range plan.Env {
-			stepEnv[k] = v
+// planRun is the state shared by the steps of one ExecutePlan call
+type planRun struct {
+	file        *schema.File
+	plan        *schema.Plan
+	planName    string
+	inv         inventory.Inventory
+	targets     []string // CLI targets, override the steps' own
+	env         map[string]string
+	result      *Result
+	artifactMgr artifacts.Manager
+	registryMgr registry.Manager
+	vars        *hostVars       // Variables registered for later steps, per host
+	failedHosts map[string]bool // Hosts that failed a step, excluded from later steps
+	state       *state.State    // Progress saved for --resume
+	mu          sync.Mutex      // Guards failedHosts, result and state while steps run concurrently
+}
+
+// stepFailure is returned by executeStep when a step fails
+type stepFailure struct {
+	step string
+	host string // First host that failed ("" if the step failed before running)
+	err  error
+}
+
+func (f *stepFailure) Error() string {
+	return f.err.Error()
+}
+
+func (f *stepFailure) Unwrap() error {
+	return f.err
+}
+
+// asStepFailure returns the step failure in err, or nil if err is nil. Any
+// other error still fails the run, as a failure of step (if known).
+func asStepFailure(err error, step string) *stepFailure {
+	if err == nil {
+		return nil
+	}
+	var failure *stepFailure
+	if errors.As(err, &failure) {
+		return failure
+	}
+	return &stepFailure{step: step, err: err}
+}
+
+// resolveHosts resolves targets to unique hosts in the step's order, optionally
+// leaving out hosts that failed an earlier step
+func (e *executor) resolveHosts(run *planRun, step schema.Step, targets []string, excludeFailed bool) ([]ssh.Host, error) {
+	hosts, err := resolveTargets(run.inv, targets, step.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	var allHosts []ssh.Host
+	for _, host := range hosts {
+		if excludeFailed && run.failedHosts[host.Name] {
+			continue
 		}
+		allHosts = append(allHosts, host)
+	}
+	return allHosts, nil
+}
 
-		// Step env overrides plan
-		for k, v := range step.Env {
-			stepEnv[k] = v
+// limitHosts keeps the first hosts the step's limit allows
+func limitHosts(step schema.Step, hosts []ssh.Host) ([]ssh.Host, error) {
+	limit, err := rollout.ParseLimit(step.Limit, len(hosts))
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && limit < len(hosts) {
+		hosts = hosts[:limit]
+	}
+	return hosts, nil
+}
+
+// resolveTargets resolves targets to unique hosts, in the order the targets
+// list them unless order says otherwise
+func resolveTargets(inv inventory.Inventory, targets []string, order string) ([]ssh.Host, error) {
+	var hosts []ssh.Host
+	seen := make(map[string]bool)
+	for _, targetName := range targets {
+		targetHosts, err := inv.ResolveTarget(targetName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve target %q: %w", targetName, err)
 		}
+		for _, host := range targetHosts {
+			if !seen[host.Name] {
+				seen[host.Name] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return rollout.OrderHosts(hosts, order)
+}
 
-		// CLI overrides everything
-		for k, v := range env {
-			stepEnv[k] = v
+// executeStep runs the step's job on hosts in batches and returns the hosts it
+// was started on. The step's own lines go to out. extraEnv overrides every
+// other env source. Finished batches are recorded in progress (nil for
+// rollback and cleanup steps).
+func (e *executor) executeStep(ctx context.Context, run *planRun, out *stepOutput, step schema.Step, allHosts []ssh.Host, targetName string, extraEnv map[string]string, progress *state.Step) ([]ssh.Host, error) {
+	result := run.result
+	totalHosts := len(allHosts)
+
+	out.ui.Info("  Job: %s", step.Job)
+	if len(step.Targets) > 0 {
+		// Determine which targets to use: CLI overrides YAML
+		stepTargets := step.Targets
+		if len(run.targets) > 0 {
+			stepTargets = run.targets
 		}
+		out.ui.Info("  Targets: %s", strings.Join(stepTargets, ", "))
+	}
+	fmt.Fprintf(out.stdout, "  Hosts: %d\n", totalHosts)
+	fmt.Fprintf(out.stdout, "  Status: %s□%s Started\n", ctc.ForegroundYellow, ctc.Reset)
+	fmt.Fprintf(out.stdout, "  Started: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
 
-		// Merge with job defaults
-		mergedEnv := loader.MergeEnv(job, stepEnv)
+	// Load job once for this step
+	job, stepEnv, mergedEnv, err := e.stepJob(run, step, extraEnv)
+	if err != nil {
+		return nil, &stepFailure{step: step.Name, err: err}
+	}
 
-		// Register artifacts for this job (loaded lazily when accessed)
-		e.loadArtifacts(job, artifactMgr)
+	// Execute all unique hosts
+	// Parse rollout strategy
+	strategy, err := rollout.ParseStrategy(string(step.Parallelism), len(allHosts))
+	if err != nil {
+		return nil, &stepFailure{step: step.Name, err: fmt.Errorf("invalid parallelism: %w", err)}
+	}
+
+	maxFail, err := rollout.ParseMaxFail(step.MaxFail, len(allHosts))
+	if err != nil {
+		return nil, &stepFailure{step: step.Name, err: fmt.Errorf("invalid max_fail: %w", err)}
+	}
+	if _, err := rollout.ParseMaxFail(step.MaxFailPerBatch, 0); err != nil {
+		return nil, &stepFailure{step: step.Name, err: fmt.Errorf("invalid max_fail_per_batch: %w", err)}
+	}
+	strategy.MaxFailPerBatch = step.MaxFailPerBatch
+	if step.MaxFail == "" && step.MaxFailPerBatch != "" {
+		// Only the per-batch budget applies
+		maxFail = totalHosts
+	}
+	var stepFailures []hostFailure
 
-		// Execute all unique hosts
-		// Parse rollout strategy
-		strategy, err := rollout.ParseStrategy(step.Parallelism, len(allHosts))
+	var pause time.Duration
+	if step.PauseBetweenBatches != "" {
+		pause, err = time.ParseDuration(step.PauseBetweenBatches)
 		if err != nil {
-			result.Failed = true
-			result.FailedStep = step.Name
-			result.Error = fmt.Errorf("invalid parallelism: %w", err)
-			return result, result.Error
+			return nil, &stepFailure{step: step.Name, err: fmt.Errorf("invalid pause_between_batches: %w", err)}
 		}
-		strategy.Limit = step.Limit
+	}
 
-		// Create batches based on strategy
-		batches := strategy.CreateBatches(allHosts)
+	// Create batches based on strategy
+	batches := strategy.CreateBatches(allHosts)
 
-		// Use first target name for logging (legacy compatibility)
-		targetName := stepTargets[0]
+	// Execute batches sequentially, hosts within batch in parallel
+	var touched []ssh.Host
+	for batchIdx, batch := range batches {
+		if batchIdx > 0 && e.stopping(ctx) {
+			fmt.Fprintf(out.stdout, "\n  Status: %s■%s Interrupted\n\n", ctc.ForegroundBlue, ctc.Reset)
+			return touched, fmt.Errorf("%w before batch %d/%d", ErrInterrupted, batchIdx+1, len(batches))
+		}
 
-		// Execute batches sequentially, hosts within batch in parallel
-		for batchIdx, batch := range batches {
-			if len(batches) > 1 {
-				fmt.Fprintf(e.stdout, "  Batch %d/%d (%d hosts)\n", batchIdx+1, len(batches), len(batch))
+		if batchIdx > 0 {
+			if err := e.betweenBatches(ctx, out, step, pause, batchIdx+1, len(batches)); err != nil {
+				if errors.Is(err, ErrInterrupted) {
+					fmt.Fprintf(out.stdout, "\n  Status: %s■%s Interrupted\n\n", ctc.ForegroundBlue, ctc.Reset)
+					return touched, err
+				}
+				fmt.Fprintf(out.stderr, "\n  Status: %s■%s Failed\n\n", ctc.ForegroundRed, ctc.Reset)
+				return touched, &stepFailure{step: step.Name, err: err}
 			}
+		}
 
-			// Execute batch in parallel
-			if err := e.executeBatch(ctx, job, step.Job, result.RunID, planName, targetName, batch, mergedEnv, artifactMgr, registryMgr); err != nil {
-				result.Failed = true
-				result.FailedStep = step.Name
-				result.Error = err
-				fmt.Fprintf(e.stderr, "\n  Status: %s■%s Failed\n\n", ctc.ForegroundRed, ctc.Reset)
-				result.EndTime = time.Now()
-				return result, result.Error
-			}
+		if len(batches) > 1 {
+			fmt.Fprintf(out.stdout, "  Batch %d/%d (%d hosts)\n", batchIdx+1, len(batches), len(batch))
+		}
 
-			if len(batches) > 1 {
-				fmt.Fprintf(e.stdout, "  ✓ Batch %d/%d completed\n", batchIdx+1, len(batches))
+		// Execute batch in parallel
+		touched = append(touched, batch...)
+		failures, err := e.executeBatch(ctx, run.file, job, step.Job, result.RunID, run.planName, targetName, batch, mergedEnv, run.artifactMgr, run.registryMgr, run.vars)
+
+		// The check must pass on the hosts that finished before the next batch starts
+		batchMaxFail, batchBudget := strategy.BatchMaxFail(len(batch))
+		withinBudget := len(stepFailures)+len(failures) <= maxFail && (!batchBudget || len(failures) <= batchMaxFail)
+		if step.BatchCheck != nil && err == nil && withinBudget {
+			var checkFailures []hostFailure
+			checkFailures, err = e.checkBatch(ctx, run, out, step, targetName, finished(batch, failures), stepEnv)
+			failures = append(failures, checkFailures...)
+		}
+		run.mu.Lock()
+		for _, failure := range failures {
+			run.failedHosts[failure.host] = true
+			result.FailedHosts = append(result.FailedHosts, failure.host)
+		}
+		run.mu.Unlock()
+		stepFailures = append(stepFailures, failures...)
+		e.recordBatch(run, progress, batch, failures, err)
+
+		// Failures over the threshold take precedence over interruptions
+		if batchBudget && len(failures) > batchMaxFail {
+			fmt.Fprintf(out.stderr, "\n  Status: %s■%s Failed\n\n", ctc.ForegroundRed, ctc.Reset)
+			return touched, &stepFailure{
+				step: step.Name,
+				host: failures[0].host,
+				err: fmt.Errorf("%d of %d hosts failed in batch %d/%d, more than max_fail_per_batch %s allows: job failed on host %s: %w",
+					len(failures), len(batch), batchIdx+1, len(batches), step.MaxFailPerBatch, failures[0].host, failures[0].err),
+			}
+		}
+		if len(stepFailures) > maxFail {
+			fmt.Fprintf(out.stderr, "\n  Status: %s■%s Failed\n\n", ctc.ForegroundRed, ctc.Reset)
+			return touched, &stepFailure{
+				step: step.Name,
+				host: stepFailures[0].host,
+				err:  stepError(stepFailures, maxFail, step.MaxFail, totalHosts),
 			}
 		}
 
-		// Step completion
-		fmt.Fprintf(e.stdout, "\n  Status: %s■%s Completed\n\n", ctc.ForegroundGreen, ctc.Reset)
+		if errors.Is(err, ErrInterrupted) {
+			fmt.Fprintf(out.stdout, "\n  Status: %s■%s Interrupted\n\n", ctc.ForegroundBlue, ctc.Reset)
+			return touched, err
+		}
+
+		if len(batches) > 1 {
+			fmt.Fprintf(out.stdout, "  ✓ Batch %d/%d completed\n", batchIdx+1, len(batches))
+		}
 	}
 
-	result.EndTime = time.Now()
-	e.ui.PlanCompleted(result.EndTime.Sub(result.StartTime))
+	// Step completion
+	if len(stepFailures) > 0 {
+		budget := "max_fail " + step.MaxFail
+		if step.MaxFail == "" {
+			budget = "max_fail_per_batch " + step.MaxFailPerBatch
+		}
+		fmt.Fprintf(out.stdout, "\n  Failed hosts: %s (%s)", strings.Join(hostNames(stepFailures), ", "), budget)
+	}
+	fmt.Fprintf(out.stdout, "\n  Status: %s■%s Completed\n\n", ctc.ForegroundGreen, ctc.Reset)
 
-	return result, nil
+	return touched, nil
 }
 
-func (e *executor) executeBatch(ctx context.Context, job *schema.Job, jobName string, runID string, plan string, target string, hosts []ssh.Host, env map[string]string, artifactMgr artifacts.Manager, registryMgr registry.Manager) error {
+// stepJob loads the step's job and returns it with the step's env and the env
+// the job runs with. Priority: extraEnv > CLI > step > plan > job defaults.
+func (e *executor) stepJob(run *planRun, step schema.Step, extraEnv map[string]string) (*schema.Job, map[string]string, map[string]string, error) {
+	job, err := e.loadJob(run.file, step.Job)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	stepEnv := make(map[string]string)
+
+	// Start with plan-level env
+	for k, v := range run.plan.Env {
+		stepEnv[k] = v
+	}
+
+	// Step env overrides plan
+	for k, v := range step.Env {
+		stepEnv[k] = v
+	}
+
+	// CLI overrides everything
+	for k, v := range run.env {
+		stepEnv[k] = v
+	}
+
+	// Set by the executor (e.g. HADES_FAILED_* for rollback and cleanup)
+	for k, v := range extraEnv {
+		stepEnv[k] = v
+	}
+
+	// Merge with job defaults
+	mergedEnv := loader.MergeEnv(job, stepEnv)
+
+	// Register artifacts for this job (loaded lazily when accessed)
+	e.loadArtifacts(run.file, job, run.artifactMgr)
+
+	return job, stepEnv, mergedEnv, nil
+}
+
+// hostFailure is a host whose job failed
+type hostFailure struct {
+	host string
+	err  error
+}
+
+func hostNames(failures []hostFailure) []string {
+	names := make([]string, len(failures))
+	for i, failure := range failures {
+		names[i] = failure.host
+	}
+	return names
+}
+
+// stepError describes the failures that aborted a step
+func stepError(failures []hostFailure, maxFail int, maxFailSpec string, totalHosts int) error {
+	first := failures[0]
+	if maxFail == 0 {
+		return fmt.Errorf("job failed on host %s: %w", first.host, first.err)
+	}
+	return fmt.Errorf("%d of %d hosts failed, more than max_fail %s allows: job failed on host %s: %w", len(failures), totalHosts, maxFailSpec, first.host, first.err)
+}
+
+// executeBatch runs the job on hosts in parallel, returning the hosts that
+// failed (sorted by name) and an interruptedError if any were cut short
+func (e *executor) executeBatch(ctx context.Context, file *schema.File, job *schema.Job, jobName string, runID string, plan string, target string, hosts []ssh.Host, env map[string]string, artifactMgr artifacts.Manager, registryMgr registry.Manager, vars *hostVars) ([]hostFailure, error) {
 	// Use channels to coordinate parallel execution
 	type result struct {
 		host ssh.Host
@@ -217,9 +631,11 @@ func (e *executor) executeBatch(ctx context.Context, job *schema.Job, jobName st
 		go func(h ssh.Host) {
 			defer wg.Done()
 
-			err := e.executeJob(ctx, job, jobName, runID, plan, target, h, env, artifactMgr, registryMgr)
+			err := e.executeJob(ctx, file, job, jobName, runID, plan, target, h, env, artifactMgr, registryMgr, vars)
 
-			if err != nil {
+			if errors.Is(err, ErrInterrupted) {
+				fmt.Fprintf(e.stdout, "[%s] %s◇%s Job %q: %v\n", h.Name, ctc.ForegroundBlue, ctc.Reset, jobName, err)
+			} else if err != nil {
 				fmt.Fprintf(e.stderr, "[%s] %s◆%s Job %q: failed - %v\n", h.Name, ctc.ForegroundRed, ctc.Reset, jobName, err)
 			} else {
 				fmt.Fprintf(e.stdout, "[%s] %s◆%s Job %q: completed\n", h.Name, ctc.ForegroundGreen, ctc.Reset, jobName)
@@ -229,24 +645,34 @@ func (e *executor) executeBatch(ctx context.Context, job *schema.Job, jobName st
 		}(host)
 	}
 
-	// Wait for all goroutines to complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
+	// Wait for all hosts so no action is left running and every log is closed
+	wg.Wait()
+	close(resultChan)
 
-	// Collect results (abort on first failure)
+	var failures []hostFailure
+	var interrupted []string
 	for res := range resultChan {
+		if errors.Is(res.err, ErrInterrupted) {
+			interrupted = append(interrupted, res.host.Name)
+			continue
+		}
 		if res.err != nil {
-			// Abort on first failure
-			return fmt.Errorf("job failed on host %s: %w", res.host.Name, res.err)
+			failures = append(failures, hostFailure{host: res.host.Name, err: res.err})
 		}
 	}
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].host < failures[j].host
+	})
 
-	return nil
+	if len(interrupted) > 0 {
+		sort.Strings(interrupted)
+		return failures, &interruptedError{hosts: interrupted}
+	}
+
+	return failures, nil
 }
 
-func (e *executor) executeJob(ctx context.Context, job *schema.Job, jobName string, runID string, plan string, target string, host ssh.Host, env map[string]string, artifactMgr artifacts.Manager, registryMgr registry.Manager) error {
+func (e *executor) executeJob(ctx context.Context, file *schema.File, job *schema.Job, jobName string, runID string, plan string, target string, host ssh.Host, env map[string]string, artifactMgr artifacts.Manager, registryMgr registry.Manager, vars *hostVars) error {
 	// Create logger for this host
 	hostLogger, err := logger.New(runID, plan, host.Name, e.stdout, e.stderr)
 	if err != nil {
@@ -254,6 +680,9 @@ func (e *executor) executeJob(ctx context.Context, job *schema.Job, jobName stri
 	}
 	defer hostLogger.Close()
 
+	// Temp files written by this run carry its ID
+	ctx = ssh.WithRunID(ctx, runID)
+
 	// Determine which client to use: local or SSH
 	var client ssh.Client
 	if job.Local {
@@ -263,10 +692,17 @@ func (e *executor) executeJob(ctx context.Context, job *schema.Job, jobName stri
 	}
 
 	// Create runtime context with logger writers and console writers
+	// Variables registered on this host by earlier steps
+	env = vars.apply(host.Name, env)
+
 	runtime := types.NewRuntime(client, artifactMgr, registryMgr, runID, plan, target, host, env, hostLogger.Stdout(), hostLogger.Stderr(), e.stdout, e.stderr)
+	if loader.ExportEnv(job) {
+		runtime.Host.Env = runtime.Env
+	}
 
 	// Evaluate guard condition first (before showing job starting)
 	if job.Guard != nil {
+		runtime.Host.Become = loader.JobBecome(job)
 		result, err := actions.EvaluateGuard(ctx, job.Guard, runtime)
 		if err != nil {
 			return fmt.Errorf("guard evaluation failed: %w", err)
@@ -282,49 +718,200 @@ func (e *executor) executeJob(ctx context.Context, job *schema.Job, jobName stri
 	// Console: Job starting (only if guard passed or no guard)
 	fmt.Fprintf(e.stdout, "[%s] %s◇%s Job %q: starting\n", host.Name, ctc.ForegroundYellow, ctc.Reset, jobName)
 
-	// Execute each action sequentially
+	return e.runActions(ctx, file, job, jobName, "", runtime, hostLogger, vars)
+}
+
+// runActions runs a job's actions in order; prefix is prepended to their
+// indices ("" for the step's job, "3." for a job included by action 3)
+func (e *executor) runActions(ctx context.Context, file *schema.File, job *schema.Job, jobName string, prefix string, runtime *types.Runtime, hostLogger *logger.Logger, vars *hostVars) error {
 	for i, actionSchema := range job.Actions {
+		index := prefix + strconv.Itoa(i)
+		if e.stopping(ctx) {
+			return fmt.Errorf("%w before action %s", ErrInterrupted, index)
+		}
+
 		// Get action type for delimiter
 		actionType := getActionType(&actionSchema)
 
 		// Format action description for console
-		actionDesc := fmt.Sprintf("[%d] %s", i, actionType)
+		actionDesc := fmt.Sprintf("[%s] %s", index, actionType)
 		if actionSchema.Name != "" {
-			actionDesc = fmt.Sprintf("[%d] %s (%s)", i, actionType, actionSchema.Name)
+			actionDesc = fmt.Sprintf("[%s] %s (%s)", index, actionType, actionSchema.Name)
+		} else if actionSchema.Job != nil {
+			actionDesc = fmt.Sprintf("[%s] %s (%s)", index, actionType, actionSchema.Job.Name)
 		}
 
 		// Set action description in runtime for use by actions
 		runtime.ActionDesc = actionDesc
+		runtime.Host.Become = loader.ActionBecome(job, &actionSchema)
 
 		// Write delimiter to log (with optional name)
-		if err := hostLogger.WriteJobDelimiter(jobName, actionType, actionSchema.Name, i); err != nil {
+		if err := hostLogger.WriteJobDelimiter(jobName, actionType, actionSchema.Name, index); err != nil {
 			return fmt.Errorf("failed to write log delimiter: %w", err)
 		}
 
-		// Console: Action starting
-		fmt.Fprintf(e.stdout, "[%s] %s◌%s Action %s: in progress\n", host.Name, ctc.ForegroundYellow, ctc.Reset, actionDesc)
+		// Evaluate the action's condition (skipped actions never start)
+		if actionSchema.When != nil {
+			pass, err := actions.EvaluateWhen(ctx, actionSchema.When, runtime)
+			if err != nil {
+				fmt.Fprintf(e.stderr, "[%s] %s●%s Action %s: failed - %v\n", runtime.Host.Name, ctc.ForegroundRed, ctc.Reset, actionDesc, err)
+				return fmt.Errorf("action %s failed: %w", index, err)
+			}
+			if !pass {
+				condition := actions.FormatWhenCondition(actionSchema.When, runtime.Env)
+				fmt.Fprintf(runtime.Stdout, "Skipping action (%s not met)\n", condition)
+				fmt.Fprintf(e.stdout, "[%s] %s○%s Action %s: skipped (%s)\n", runtime.Host.Name, ctc.ForegroundBlue, ctc.Reset, actionDesc, condition)
+				continue
+			}
+		}
+
+		// Included job: its actions run here as [index.N]
+		if actionSchema.Job != nil {
+			fmt.Fprintf(e.stdout, "[%s] %s◌%s Action %s: in progress\n", runtime.Host.Name, ctc.ForegroundYellow, ctc.Reset, actionDesc)
+			err := e.includeJob(ctx, file, actionSchema.Job, index, runtime, hostLogger, vars)
+			if errors.Is(err, ErrInterrupted) {
+				return err
+			}
+			if err != nil && actionSchema.IgnoreErrors {
+				fmt.Fprintf(runtime.Stderr, "Action failed (ignored): %v\n", err)
+				fmt.Fprintf(e.stdout, "[%s] %s●%s Action %s: failed (ignored) - %v\n", runtime.Host.Name, ctc.ForegroundYellow, ctc.Reset, actionDesc, err)
+				continue
+			}
+			if err != nil {
+				fmt.Fprintf(e.stderr, "[%s] %s●%s Action %s: failed - %v\n", runtime.Host.Name, ctc.ForegroundRed, ctc.Reset, actionDesc, err)
+				return err
+			}
+			fmt.Fprintf(e.stdout, "[%s] %s●%s Action %s: completed\n", runtime.Host.Name, ctc.ForegroundGreen, ctc.Reset, actionDesc)
+			continue
+		}
 
 		action, err := e.createAction(&actionSchema, hostLogger)
 		if err != nil {
-			return fmt.Errorf("action %d: %w", i, err)
+			return fmt.Errorf("action %s: %w", index, err)
 		}
 
-		if err := action.Execute(ctx, runtime); err != nil {
+		timeout, err := loader.ActionTimeout(job, &actionSchema)
+		if err != nil {
+			return fmt.Errorf("action %s: %w", index, err)
+		}
+
+		retry, err := loader.ActionRetry(&actionSchema)
+		if err != nil {
+			return fmt.Errorf("action %s: %w", index, err)
+		}
+
+		// Console: Action starting
+		fmt.Fprintf(e.stdout, "[%s] %s◌%s Action %s: in progress\n", runtime.Host.Name, ctc.ForegroundYellow, ctc.Reset, actionDesc)
+
+		attempt := 1
+		for {
+			err = executeAction(ctx, action, runtime, timeout)
+			if err == nil && actionSchema.Until != "" {
+				err = checkUntil(ctx, actionSchema.Until, runtime)
+			}
+			if err == nil || attempt == retry.Attempts || ctx.Err() != nil {
+				break
+			}
+
+			// Log and console: Attempt failed, retrying after the delay
+			delay := retry.DelayBefore(attempt + 1)
+			fmt.Fprintf(runtime.Stderr, "Attempt %d/%d failed: %v\n", attempt, retry.Attempts, err)
+			fmt.Fprintf(e.stdout, "[%s] %s◌%s Action %s: retrying in %s (attempt %d/%d failed - %v)\n", runtime.Host.Name, ctc.ForegroundYellow, ctc.Reset, actionDesc, delay, attempt, retry.Attempts, err)
+			if !e.sleep(ctx, delay) {
+				fmt.Fprintf(e.stderr, "[%s] %s●%s Action %s: interrupted\n", runtime.Host.Name, ctc.ForegroundBlue, ctc.Reset, actionDesc)
+				return fmt.Errorf("%w before retrying action %s", ErrInterrupted, index)
+			}
+
+			attempt++
+			if err := hostLogger.WriteJobDelimiter(jobName, actionType, attemptName(actionSchema.Name, attempt, retry.Attempts), index); err != nil {
+				return fmt.Errorf("failed to write log delimiter: %w", err)
+			}
+		}
+
+		if actionSchema.Register != "" && actionSchema.RegisterScope == loader.RegisterScopePlan {
+			vars.set(runtime.Host.Name, actionSchema.Register, runtime.Env)
+		}
+
+		// Attempt count, shown once an action has been retried
+		attempts := ""
+		if attempt > 1 {
+			attempts = fmt.Sprintf(" (attempt %d/%d)", attempt, retry.Attempts)
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				// Console: Action cancelled by a forced interrupt
+				fmt.Fprintf(e.stderr, "[%s] %s●%s Action %s: interrupted\n", runtime.Host.Name, ctc.ForegroundBlue, ctc.Reset, actionDesc)
+				return fmt.Errorf("%w during action %s", ErrInterrupted, index)
+			}
+
+			if actionSchema.IgnoreErrors {
+				// Log and console: Failure tolerated, the job goes on
+				fmt.Fprintf(runtime.Stderr, "Action failed (ignored): %v\n", err)
+				fmt.Fprintf(e.stdout, "[%s] %s●%s Action %s: failed%s (ignored) - %v\n", runtime.Host.Name, ctc.ForegroundYellow, ctc.Reset, actionDesc, attempts, err)
+				continue
+			}
+
+			var timeoutErr *TimeoutError
+			if errors.As(err, &timeoutErr) {
+				// Log and console: Action timed out (distinct from a failure)
+				fmt.Fprintf(runtime.Stderr, "Action %s\n", timeoutErr)
+				fmt.Fprintf(e.stderr, "[%s] %s●%s Action %s: %s%s\n", runtime.Host.Name, ctc.ForegroundRed, ctc.Reset, actionDesc, timeoutErr, attempts)
+				return fmt.Errorf("action %s %w", index, err)
+			}
+
 			// Console: Action failed
-			fmt.Fprintf(e.stderr, "[%s] %s●%s Action %s: failed - %v\n", host.Name, ctc.ForegroundRed, ctc.Reset, actionDesc, err)
-			return fmt.Errorf("action %d failed: %w", i, err)
+			fmt.Fprintf(e.stderr, "[%s] %s●%s Action %s: failed%s - %v\n", runtime.Host.Name, ctc.ForegroundRed, ctc.Reset, actionDesc, attempts, err)
+			return fmt.Errorf("action %s failed: %w", index, err)
 		}
 
 		// Console: Action completed
-		fmt.Fprintf(e.stdout, "[%s] %s●%s Action %s: completed\n", host.Name, ctc.ForegroundGreen, ctc.Reset, actionDesc)
+		fmt.Fprintf(e.stdout, "[%s] %s●%s Action %s: completed%s\n", runtime.Host.Name, ctc.ForegroundGreen, ctc.Reset, actionDesc, attempts)
 	}
 
 	return nil
 }
 
+// checkUntil runs an action's until test, failing the attempt unless it passes
+func checkUntil(ctx context.Context, until string, runtime *types.Runtime) error {
+	result, err := actions.EvaluateGuard(ctx, &schema.Guard{If: until}, runtime)
+	if err != nil {
+		return fmt.Errorf("until evaluation failed: %w", err)
+	}
+	if !result.Pass {
+		return fmt.Errorf("until condition not met: %s", actions.ExpandEnvVars(until, runtime.Env))
+	}
+	return nil
+}
+
+// attemptName labels a retry in the log delimiter
+func attemptName(name string, attempt, attempts int) string {
+	label := fmt.Sprintf("attempt %d/%d", attempt, attempts)
+	if name == "" {
+		return label
+	}
+	return name + ", " + label
+}
+
+// executeAction runs action, cancelling it once timeout expires (0 = no timeout)
+func executeAction(ctx context.Context, action actions.Action, runtime *types.Runtime, timeout time.Duration) error {
+	if timeout <= 0 {
+		return action.Execute(ctx, runtime)
+	}
+
+	actionCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := action.Execute(actionCtx, runtime)
+	if err != nil && ctx.Err() == nil && actionCtx.Err() == context.DeadlineExceeded {
+		return &TimeoutError{Timeout: timeout}
+	}
+	return err
+}
+
 func (e *executor) createAction(actionSchema *schema.Action, planLogger *logger.Logger) (actions.Action, error) {
 	if actionSchema.Run != nil {
-		return actions.NewRunAction(actionSchema.Run), nil
+		return actions.NewRunAction(actionSchema.Run, actionSchema.Register), nil
 	}
 	if actionSchema.Copy != nil {
 		return actions.NewCopyAction(actionSchema.Copy), nil
@@ -377,14 +964,27 @@ func getActionType(actionSchema *schema.Action) string {
 	if actionSchema.Gpg != nil {
 		return "gpg"
 	}
+	if actionSchema.Job != nil {
+		return "job"
+	}
 	return "unknown"
 }
 
-func (e *executor) loadArtifacts(job *schema.Job, artifactMgr artifacts.Manager) {
+func (e *executor) loadArtifacts(file *schema.File, job *schema.Job, artifactMgr artifacts.Manager) {
 	// Register artifacts defined in the job (loaded lazily on first access)
 	for name, artifact := range job.Artifacts {
 		artifactMgr.Register(name, artifact.Path)
 	}
+
+	// And in the jobs it includes (cycles are rejected by Validate)
+	for _, action := range job.Actions {
+		if action.Job == nil {
+			continue
+		}
+		if included, ok := file.Jobs[action.Job.Name]; ok {
+			e.loadArtifacts(file, &included, artifactMgr)
+		}
+	}
 }
 
 func (e *executor) loadJob(file *schema.File, name string) (*schema.Job, error) {
@@ -395,7 +995,7 @@ func (e *executor) loadJob(file *schema.File, name string) (*schema.Job, error)
 	return &job, nil
 }
 
-func (e *executor) DryRun(ctx context.Context, file *schema.File, plan *schema.Plan, planName string, inv inventory.Inventory, targets []string, env map[string]string) error {
+func (e *executor) DryRun(ctx context.Context, file *schema.File, plan *schema.Plan, planName string, inv inventory.Inventory, targets []string, env map[string]string, opts RunOptions) error {
 	// Create artifact manager for dry-run (won't actually load artifacts)
 	artifactMgr := artifacts.NewManager()
 
@@ -407,8 +1007,36 @@ func (e *executor) DryRun(ctx context.Context, file *schema.File, plan *schema.P
 
 	e.ui.DryRunHeader(planName)
 
+	// Steps of the same level run concurrently
+	if loader.UsesNeeds(plan) {
+		levels, err := loader.StepLevels(plan)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(e.stdout, "Execution order:\n")
+		for i, level := range levels {
+			fmt.Fprintf(e.stdout, "  %d. %s\n", i+1, strings.Join(level, ", "))
+		}
+		fmt.Fprintln(e.stdout)
+	}
+
+	// Each host runs through its steps on its own, so there are no batches
+	perHost := plan.Strategy == schema.StrategyPerHost
+	if perHost {
+		concurrency := plan.Concurrency
+		if concurrency == "" {
+			concurrency = "all hosts"
+		}
+		fmt.Fprintf(e.stdout, "Strategy: %s (concurrency: %s)\n\n", schema.StrategyPerHost, concurrency)
+	}
+
 	// Iterate steps
 	for i, step := range plan.Steps {
+		if len(opts.Steps) > 0 && !slices.Contains(opts.Steps, step.Name) {
+			fmt.Fprintf(e.stdout, "Step %d: %s (skipped)\n\n", i+1, step.Name)
+			continue
+		}
+
 		// Determine which targets to use: CLI overrides YAML
 		stepTargets := step.Targets
 		if len(targets) > 0 {
@@ -417,29 +1045,85 @@ func (e *executor) DryRun(ctx context.Context, file *schema.File, plan *schema.P
 
 		fmt.Fprintf(e.stdout, "Step %d: %s\n", i+1, step.Name)
 		fmt.Fprintf(e.stdout, "  Job: %s\n", step.Job)
+		switch {
+		case len(step.Needs) > 0:
+			fmt.Fprintf(e.stdout, "  Needs: %s\n", strings.Join(step.Needs, ", "))
+		case step.Needs != nil:
+			fmt.Fprintf(e.stdout, "  Needs: none (starts right away)\n")
+		}
+		if step.Rollback != "" {
+			fmt.Fprintf(e.stdout, "  Rollback: %s\n", step.Rollback)
+		}
 		fmt.Fprintf(e.stdout, "  Targets: %s\n", strings.Join(stepTargets, ", "))
 
-		// Resolve hosts and deduplicate
-		uniqueHosts := make(map[string]ssh.Host) // keyed by host name
-		for _, targetName := range stepTargets {
-			targetHosts, err := inv.ResolveTarget(targetName)
-			if err != nil {
-				return fmt.Errorf("failed to resolve target %q: %w", targetName, err)
-			}
-			// Add hosts to unique set
-			for _, host := range targetHosts {
-				uniqueHosts[host.Name] = host
+		// Resolve hosts, deduplicated and in the step's order
+		hosts, err := resolveTargets(inv, stepTargets, step.Order)
+		if err != nil {
+			return err
+		}
+		hosts, err = limitHosts(step, hosts)
+		if err != nil {
+			return fmt.Errorf("step %q: %w", step.Name, err)
+		}
+
+		// A resumed step keeps its hosts, less those that completed it
+		if opts.Resume != nil {
+			if stepState := opts.Resume.Step(step.Name); stepState != nil && len(stepState.Hosts) > 0 {
+				hosts, err = resumeHosts(inv, stepState)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(e.stdout, "  Resuming: %d of %d host(s) already completed\n", len(stepState.Completed), len(stepState.Hosts))
 			}
 		}
 
-		// Convert map to slice
-		var hosts []ssh.Host
-		for _, host := range uniqueHosts {
-			hosts = append(hosts, host)
+		if step.Order != "" {
+			fmt.Fprintf(e.stdout, "  Order: %s\n", step.Order)
+		}
+		strategy, err := rollout.ParseStrategy(string(step.Parallelism), len(hosts))
+		if err != nil {
+			return fmt.Errorf("step %q: invalid parallelism: %w", step.Name, err)
+		}
+		strategy.MaxFailPerBatch = step.MaxFailPerBatch
+		if len(strategy.Stages) > 0 {
+			fmt.Fprintf(e.stdout, "  Stages: %s\n", step.Parallelism)
+		}
+		if step.MaxFail != "" {
+			fmt.Fprintf(e.stdout, "  Max fail: %s\n", step.MaxFail)
+		}
+		if perHost {
+			var names []string
+			for _, host := range hosts {
+				names = append(names, host.Name)
+			}
+			fmt.Fprintf(e.stdout, "  Hosts: %s\n", strings.Join(names, ", "))
+		} else {
+			batches := strategy.CreateBatches(hosts)
+			for batchIdx, batch := range batches {
+				var names []string
+				for _, host := range batch {
+					names = append(names, host.Name)
+				}
+				budget := ""
+				if batchMaxFail, ok := strategy.BatchMaxFail(len(batch)); ok {
+					budget = fmt.Sprintf(" (may fail: %d)", batchMaxFail)
+				}
+				fmt.Fprintf(e.stdout, "  Batch %d/%d: %s%s\n", batchIdx+1, len(batches), strings.Join(names, ", "), budget)
+			}
 		}
 
-		if step.Limit > 0 && step.Limit < len(hosts) {
-			hosts = hosts[:step.Limit]
+		var gates []string
+		if step.PauseBetweenBatches != "" {
+			gates = append(gates, "pause "+step.PauseBetweenBatches)
+		}
+		if step.ConfirmBetweenBatches {
+			gates = append(gates, "confirm")
+		}
+		if len(gates) > 0 {
+			fmt.Fprintf(e.stdout, "  Between batches: %s\n", strings.Join(gates, ", "))
+		}
+		if step.BatchCheck != nil {
+			fmt.Fprintf(e.stdout, "  Batch check: %s\n", formatBatchCheck(step.BatchCheck))
 		}
 
 		// Load job
@@ -482,17 +1166,82 @@ func (e *executor) DryRun(ctx context.Context, file *schema.File, plan *schema.P
 			runtime := types.NewRuntime(client, artifactMgr, registryMgr, "dry-run", planName, stepTargets[0], host, mergedEnv, e.stdout, e.stderr, e.stdout, e.stderr)
 
 			fmt.Fprintf(e.stdout, "\n  [%s]\n", host.Name)
-			for _, actionSchema := range job.Actions {
-				action, err := e.createAction(&actionSchema, nil)
-				if err != nil {
-					return err
-				}
-				fmt.Fprintf(e.stdout, "    - %s\n", action.DryRun(ctx, runtime))
+			if err := e.dryRunActions(ctx, file, job, runtime, "    "); err != nil {
+				return err
 			}
 		}
 
 		fmt.Fprintf(e.stdout, "\n")
 	}
 
+	dryRunCleanup(e.stdout, "On failure", plan.OnFailure)
+	dryRunCleanup(e.stdout, "Always", plan.Always)
+
 	return nil
 }
+
+// dryRunActions lists a job's actions, each line starting with indent
+func (e *executor) dryRunActions(ctx context.Context, file *schema.File, job *schema.Job, runtime *types.Runtime, indent string) error {
+	for _, actionSchema := range job.Actions {
+		if actionSchema.Job != nil {
+			desc := "job: " + actionSchema.Job.Name
+			if actionSchema.When != nil {
+				desc += " (" + actions.FormatWhenCondition(actionSchema.When, runtime.Env) + ")"
+			}
+			fmt.Fprintf(e.stdout, "%s- %s\n", indent, desc)
+			if err := e.dryRunInclude(ctx, file, actionSchema.Job, runtime, indent+"  "); err != nil {
+				return err
+			}
+			continue
+		}
+
+		action, err := e.createAction(&actionSchema, nil)
+		if err != nil {
+			return err
+		}
+		timeout, err := loader.ActionTimeout(job, &actionSchema)
+		if err != nil {
+			return err
+		}
+		runtime.Host.Become = loader.ActionBecome(job, &actionSchema)
+
+		var options []string
+		if runtime.Host.Become != "" {
+			options = append(options, "become: "+runtime.Host.Become)
+		}
+		if actionSchema.When != nil {
+			options = append(options, actions.FormatWhenCondition(actionSchema.When, runtime.Env))
+		}
+		if timeout > 0 {
+			options = append(options, "timeout: "+timeout.String())
+		}
+		if actionSchema.Retries > 0 {
+			options = append(options, fmt.Sprintf("retries: %d", actionSchema.Retries))
+		}
+		if actionSchema.Until != "" {
+			options = append(options, "until: "+actions.ExpandEnvVars(actionSchema.Until, runtime.Env))
+		}
+		if len(options) > 0 {
+			fmt.Fprintf(e.stdout, "%s- %s (%s)\n", indent, action.DryRun(ctx, runtime), strings.Join(options, ", "))
+		} else {
+			fmt.Fprintf(e.stdout, "%s- %s\n", indent, action.DryRun(ctx, runtime))
+		}
+	}
+	return nil
+}
+
+// dryRunCleanup lists on_failure or always steps
+func dryRunCleanup(w io.Writer, title string, steps []schema.Step) {
+	if len(steps) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, step := range steps {
+		targets := "hosts the plan ran on"
+		if len(step.Targets) > 0 {
+			targets = strings.Join(step.Targets, ", ")
+		}
+		fmt.Fprintf(w, "  - %s (job: %s, targets: %s)\n", step.Name, step.Job, targets)
+	}
+	fmt.Fprintf(w, "\n")
+}
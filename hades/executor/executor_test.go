@@ -0,0 +1,1109 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+	"github.com/SoftKiwiGames/hades/hades/state"
+)
+
+// staticInventory resolves every target to the same hosts
+type staticInventory []ssh.Host
+
+func (i staticInventory) ResolveTarget(name string) ([]ssh.Host, error) { return i, nil }
+func (i staticInventory) AllHosts() []ssh.Host                          { return i }
+func (i staticInventory) DynamicHosts() []ssh.Host                      { return nil }
+
+func runAction(cmd string) schema.Action {
+	return schema.Action{Run: &schema.ActionRun{Cmd: cmd}}
+}
+
+// newTestPlan builds a local two-step plan; the first action of "slow" sleeps
+func newTestPlan(marker string) (*schema.File, *schema.Plan) {
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"slow": {
+				Local: true,
+				Actions: []schema.Action{
+					runAction("sleep 0.5"),
+					runAction("touch " + marker),
+				},
+			},
+			"next": {
+				Local:   true,
+				Actions: []schema.Action{runAction("touch " + marker)},
+			},
+		},
+	}
+	plan := &schema.Plan{
+		Steps: []schema.Step{
+			{Name: "first", Job: "slow", Targets: []string{"local"}},
+			{Name: "second", Job: "next", Targets: []string{"local"}},
+		},
+	}
+	return file, plan
+}
+
+func TestExecutePlan_Interrupt(t *testing.T) {
+	t.Chdir(t.TempDir())
+	marker := filepath.Join(t.TempDir(), "marker")
+	file, plan := newTestPlan(marker)
+
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		exec.Interrupt()
+	}()
+
+	result, err := exec.ExecutePlan(context.Background(), file, plan, "test", staticInventory{{Name: "local"}}, nil, nil, RunOptions{})
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("Expected ErrInterrupted, got %v", err)
+	}
+	if !result.Interrupted || result.Failed {
+		t.Errorf("Expected interrupted (not failed) result, got %+v", result)
+	}
+	if result.InterruptedStep != "first" {
+		t.Errorf("Expected interrupted step %q, got %q", "first", result.InterruptedStep)
+	}
+	if len(result.InterruptedHosts) != 1 || result.InterruptedHosts[0] != "local" {
+		t.Errorf("Expected interrupted hosts [local], got %v", result.InterruptedHosts)
+	}
+	if time.Since(result.StartTime) < 500*time.Millisecond {
+		t.Error("Expected the running action to finish before returning")
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("Expected no actions to start after the interrupt")
+	}
+}
+
+func TestExecutePlan_Cancelled(t *testing.T) {
+	t.Chdir(t.TempDir())
+	marker := filepath.Join(t.TempDir(), "marker")
+	file, plan := newTestPlan(marker)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	result, err := exec.ExecutePlan(ctx, file, plan, "test", staticInventory{{Name: "local"}}, nil, nil, RunOptions{})
+	if !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("Expected ErrInterrupted, got %v", err)
+	}
+	if !result.Interrupted || result.InterruptedStep != "first" {
+		t.Errorf("Expected interrupt in step %q, got %+v", "first", result)
+	}
+	if time.Since(result.StartTime) >= 500*time.Millisecond {
+		t.Error("Expected cancellation to stop the running action")
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("Expected no actions to start after cancellation")
+	}
+}
+
+func TestExecutePlan_Register(t *testing.T) {
+	t.Chdir(t.TempDir())
+	out := filepath.Join(t.TempDir(), "out")
+
+	register := func(cmd, name, scope string) schema.Action {
+		action := runAction(cmd)
+		action.Register = name
+		action.RegisterScope = scope
+		return action
+	}
+
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"produce": {
+				Local: true,
+				Actions: []schema.Action{
+					register("echo v1.2.3", "RELEASE", ""),
+					register("echo token; echo warn >&2", "TOKEN", "plan"),
+					runAction(`echo "${RELEASE} $TOKEN $TOKEN_STDERR $TOKEN_RC" > ` + out),
+				},
+			},
+			"consume": {
+				Local:   true,
+				Actions: []schema.Action{runAction(`echo "${RELEASE}|${TOKEN}" >> ` + out)},
+			},
+		},
+	}
+	plan := &schema.Plan{
+		Steps: []schema.Step{
+			{Name: "produce", Job: "produce", Targets: []string{"local"}},
+			{Name: "consume", Job: "consume", Targets: []string{"local"}},
+		},
+	}
+
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	if _, err := exec.ExecutePlan(context.Background(), file, plan, "test", staticInventory{{Name: "local"}}, nil, nil, RunOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// RELEASE is job-scoped, so the second step sees it unexpanded (and empty in the shell)
+	want := "v1.2.3 token warn 0\n|token\n"
+	if string(data) != want {
+		t.Errorf("Expected %q, got %q", want, string(data))
+	}
+}
+
+func TestExecutePlan_When(t *testing.T) {
+	t.Chdir(t.TempDir())
+	dir := t.TempDir()
+
+	when := func(cmd string, cond schema.When) schema.Action {
+		action := runAction(cmd)
+		action.When = &cond
+		return action
+	}
+
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"deploy": {
+				Local: true,
+				Env:   map[string]schema.Env{"APP_ENV": {Default: "staging"}},
+				Actions: []schema.Action{
+					when("touch "+filepath.Join(dir, "prod"), schema.When{Expr: `APP_ENV == "prod"`}),
+					when("touch "+filepath.Join(dir, "staging"), schema.When{Expr: `APP_ENV == "staging"`}),
+					when("touch "+filepath.Join(dir, "missing"), schema.When{If: "test -f " + filepath.Join(dir, "nope")}),
+					when("touch "+filepath.Join(dir, "present"), schema.When{If: "test -f " + filepath.Join(dir, "staging")}),
+				},
+			},
+		},
+	}
+	plan := &schema.Plan{
+		Steps: []schema.Step{{Name: "deploy", Job: "deploy", Targets: []string{"local"}}},
+	}
+
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	if _, err := exec.ExecutePlan(context.Background(), file, plan, "test", staticInventory{{Name: "local"}}, nil, nil, RunOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for name, wantRun := range map[string]bool{"prod": false, "staging": true, "missing": false, "present": true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if ran := err == nil; ran != wantRun {
+			t.Errorf("Expected action %q ran=%v, got %v", name, wantRun, ran)
+		}
+	}
+}
+
+func TestExecutePlan_Retries(t *testing.T) {
+	t.Chdir(t.TempDir())
+	counter := filepath.Join(t.TempDir(), "attempts")
+
+	// Fails until the third attempt
+	flaky := runAction(`echo x >> ` + counter + ` && test $(wc -l < ` + counter + `) -ge 3`)
+	flaky.Retries = 3
+	flaky.Delay = "10ms"
+
+	// Succeeds every time, but until only passes on the second attempt
+	untilCounter := counter + "-until"
+	polled := runAction(`echo x >> ` + untilCounter)
+	polled.Retries = 2
+	polled.Until = `test $(wc -l < ` + untilCounter + `) -ge 2`
+
+	exhausted := runAction("false")
+	exhausted.Retries = 1
+
+	tests := []struct {
+		name    string
+		action  schema.Action
+		file    string
+		want    int
+		wantErr bool
+	}{
+		{name: "retries until success", action: flaky, file: counter, want: 3},
+		{name: "until", action: polled, file: untilCounter, want: 2},
+		{name: "exhausted", action: exhausted, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &schema.File{Jobs: map[string]schema.Job{"job": {Local: true, Actions: []schema.Action{tt.action}}}}
+			plan := &schema.Plan{Steps: []schema.Step{{Name: "step", Job: "job", Targets: []string{"local"}}}}
+
+			exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+			_, err := exec.ExecutePlan(context.Background(), file, plan, "test", staticInventory{{Name: "local"}}, nil, nil, RunOptions{})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error after the last attempt")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := bytes.Count(data, []byte("\n")); got != tt.want {
+				t.Errorf("Expected %d attempts, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecutePlan_MaxFail(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name         string
+		maxFail      string
+		batchMaxFail string
+		parallelism  schema.Parallelism
+		wantFailed   bool
+		wantSecond   string // Hosts that ran the second step
+	}{
+		{name: "no failures tolerated", maxFail: "", wantFailed: true},
+		{name: "within threshold", maxFail: "1", wantSecond: "a\nc\n"},
+		{name: "within percentage", maxFail: "50%", wantSecond: "a\nc\n"},
+		{name: "percentage exceeded", maxFail: "30%", wantFailed: true},
+		{name: "within batch budget", batchMaxFail: "50%", parallelism: "1, 100%", wantSecond: "a\nc\n"},
+		{name: "batch budget exceeded", batchMaxFail: "0", parallelism: "1, 100%", wantFailed: true},
+		{name: "total exceeded within batch budget", maxFail: "0", batchMaxFail: "1", parallelism: "1, 100%", wantFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			second := filepath.Join(t.TempDir(), "second")
+			file := &schema.File{
+				Jobs: map[string]schema.Job{
+					"flaky": {Local: true, Actions: []schema.Action{runAction(`test "$HADES_HOST_NAME" != b`)}},
+					"next":  {Local: true, Actions: []schema.Action{runAction(`echo "$HADES_HOST_NAME" >> ` + second)}},
+				},
+			}
+			plan := &schema.Plan{
+				Steps: []schema.Step{
+					{Name: "first", Job: "flaky", Targets: []string{"all"}, MaxFail: tt.maxFail, MaxFailPerBatch: tt.batchMaxFail, Parallelism: tt.parallelism},
+					{Name: "second", Job: "next", Targets: []string{"all"}, Parallelism: "1"},
+				},
+			}
+
+			exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+			inv := staticInventory{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+			result, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, nil, RunOptions{})
+
+			if len(result.FailedHosts) != 1 || result.FailedHosts[0] != "b" {
+				t.Errorf("Expected failed hosts [b], got %v", result.FailedHosts)
+			}
+			if tt.wantFailed {
+				if err == nil || !result.Failed || result.FailedStep != "first" || result.FailedHost != "b" {
+					t.Errorf("Expected step %q to fail on host b, got %+v", "first", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sortedLines(string(data)); got != tt.wantSecond {
+				t.Errorf("Expected %q, got %q", tt.wantSecond, got)
+			}
+		})
+	}
+}
+
+func TestExecutePlan_IgnoreErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+	marker := filepath.Join(t.TempDir(), "marker")
+
+	failing := runAction("false")
+	failing.IgnoreErrors = true
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"job": {Local: true, Actions: []schema.Action{failing, runAction("touch " + marker)}},
+		},
+	}
+	plan := &schema.Plan{Steps: []schema.Step{{Name: "step", Job: "job", Targets: []string{"local"}}}}
+
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	result, err := exec.ExecutePlan(context.Background(), file, plan, "test", staticInventory{{Name: "local"}}, nil, nil, RunOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.FailedHosts) != 0 {
+		t.Errorf("Expected no failed hosts, got %v", result.FailedHosts)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Error("Expected the job to continue after the ignored failure")
+	}
+}
+
+func sortedLines(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestExecutePlan_RollbackAndCleanup(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name       string
+		failOn     string
+		wantFailed bool
+		want       string
+	}{
+		{
+			name:       "failure",
+			failOn:     "b",
+			wantFailed: true,
+			want: "rollback a migrate\nrollback b migrate\n" +
+				"on_failure a migrate b\non_failure b migrate b\n" +
+				"always a migrate\nalways b migrate\n",
+		},
+		{
+			name:   "success",
+			failOn: "none",
+			want:   "always a \nalways b \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			record := func(line string) schema.Job {
+				return schema.Job{Local: true, Actions: []schema.Action{runAction(`echo "` + line + `" >> ` + out)}}
+			}
+
+			file := &schema.File{
+				Jobs: map[string]schema.Job{
+					"deploy":      {Local: true, Actions: []schema.Action{runAction("true")}},
+					"migrate":     {Local: true, Actions: []schema.Action{runAction(`test "$HADES_HOST_NAME" != ` + tt.failOn)}},
+					"undeploy":    record(`rollback $HADES_HOST_NAME $HADES_FAILED_STEP`),
+					"notify":      record(`on_failure $HADES_HOST_NAME $HADES_FAILED_STEP $HADES_FAILED_HOST`),
+					"cleanup":     record(`always $HADES_HOST_NAME $HADES_FAILED_STEP`),
+					"unreachable": record("unreachable"),
+				},
+			}
+			plan := &schema.Plan{
+				Steps: []schema.Step{
+					{Name: "deploy", Job: "deploy", Targets: []string{"all"}, Rollback: "undeploy", Parallelism: "1"},
+					{Name: "migrate", Job: "migrate", Targets: []string{"all"}, Parallelism: "1"},
+				},
+				OnFailure: []schema.Step{{Name: "notify", Job: "notify", Parallelism: "1"}},
+				Always:    []schema.Step{{Name: "cleanup", Job: "cleanup", Parallelism: "1"}},
+			}
+
+			exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+			inv := staticInventory{{Name: "a"}, {Name: "b"}}
+			result, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, nil, RunOptions{})
+			if tt.wantFailed {
+				if err == nil || result.FailedStep != "migrate" || result.FailedHost != "b" {
+					t.Errorf("Expected step %q to fail on host b, got %+v", "migrate", result)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sortedLines(string(data)); got != sortedLines(tt.want) {
+				t.Errorf("Expected %q, got %q", sortedLines(tt.want), got)
+			}
+		})
+	}
+}
+
+func TestExecutePlan_Resume(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	fixed := filepath.Join(dir, "fixed")
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"a": {Local: true, Actions: []schema.Action{runAction(`echo "a $HADES_HOST_NAME" >> ` + out)}},
+			"b": {Local: true, Actions: []schema.Action{
+				runAction(`test "$HADES_HOST_NAME" != y || test -f ` + fixed),
+				runAction(`echo "b $HADES_HOST_NAME" >> ` + out),
+			}},
+		},
+	}
+	plan := &schema.Plan{
+		Steps: []schema.Step{
+			{Name: "a", Job: "a", Targets: []string{"all"}, Parallelism: "2"},
+			{Name: "b", Job: "b", Targets: []string{"all"}, Parallelism: "2"},
+		},
+	}
+	inv := staticInventory{{Name: "x"}, {Name: "y"}}
+
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	result, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, map[string]string{"VERSION": "v1"}, RunOptions{})
+	if err == nil {
+		t.Fatalf("Expected step b to fail on host y")
+	}
+
+	st, err := state.Load(result.RunID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if st.Status != state.StatusFailed || st.NextStep() != "b" || st.Env["VERSION"] != "v1" {
+		t.Errorf("Expected failed run to resume at step b with its env, got %+v", st)
+	}
+	if got := st.Step("b").Completed; len(got) != 1 || got[0] != "x" {
+		t.Errorf("Expected only x to have completed step b, got %v", got)
+	}
+
+	if err := os.WriteFile(fixed, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	exec = New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	resumed, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, st.Env, RunOptions{Steps: []string{"b"}, Resume: st})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resumed.RunID != result.RunID {
+		t.Errorf("Expected %q, got %q", result.RunID, resumed.RunID)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a x\na y\nb x\nb y\n"
+	if got := sortedLines(string(data)); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	st, err = state.Load(result.RunID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if st.Status != state.StatusCompleted {
+		t.Errorf("Expected %q, got %q", state.StatusCompleted, st.Status)
+	}
+}
+
+func TestExecutePlan_SavesRawEnv(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("DEPLOY_TOKEN", "secret")
+
+	file, plan := newTestPlan(filepath.Join(t.TempDir(), "marker"))
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	opts := RunOptions{RawEnv: map[string]string{"TOKEN": "${DEPLOY_TOKEN}"}}
+	result, err := exec.ExecutePlan(context.Background(), file, plan, "test", staticInventory{{Name: "local"}}, nil, map[string]string{"TOKEN": "secret"}, opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	st, err := state.Load(result.RunID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := st.Env["TOKEN"]; got != "${DEPLOY_TOKEN}" {
+		t.Errorf("Expected %q, got %q", "${DEPLOY_TOKEN}", got)
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	first, second := newRunID(), newRunID()
+	if first == second {
+		t.Errorf("Expected run IDs started in the same second to differ, got %q twice", first)
+	}
+	if !strings.HasPrefix(first, "hades-") || len(first) != len("hades-20060102-150405-abcdef") {
+		t.Errorf("Unexpected run ID format %q", first)
+	}
+}
+
+func TestAsStepFailure(t *testing.T) {
+	failure := &stepFailure{step: "deploy", host: "web-1", err: errors.New("boom")}
+
+	tests := []struct {
+		name     string
+		err      error
+		wantStep string
+		wantHost string
+		wantNil  bool
+	}{
+		{name: "no error", wantNil: true},
+		{name: "step failure", err: failure, wantStep: "deploy", wantHost: "web-1"},
+		{name: "wrapped step failure", err: fmt.Errorf("cleanup: %w", failure), wantStep: "deploy", wantHost: "web-1"},
+		{name: "other error", err: errors.New("unexpected"), wantStep: "build"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := asStepFailure(tt.err, "build")
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("Expected nil, got %+v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("Expected a failure, got nil")
+			}
+			if got.step != tt.wantStep || got.host != tt.wantHost || got.err == nil {
+				t.Errorf("Expected step %q host %q, got %+v", tt.wantStep, tt.wantHost, got)
+			}
+		})
+	}
+}
+
+func TestExecutePlan_ResumeKeepsRegisters(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	fixed := filepath.Join(dir, "fixed")
+	probe := runAction("echo token")
+	probe.Register = "TOKEN"
+	probe.RegisterScope = "plan"
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"probe": {Local: true, Actions: []schema.Action{probe}},
+			"use": {Local: true, Actions: []schema.Action{
+				runAction("test -f " + fixed),
+				runAction(`echo "$TOKEN" > ` + out),
+			}},
+		},
+	}
+	plan := &schema.Plan{
+		Steps: []schema.Step{
+			{Name: "probe", Job: "probe", Targets: []string{"local"}},
+			{Name: "use", Job: "use", Targets: []string{"local"}},
+		},
+	}
+	inv := staticInventory{{Name: "local"}}
+
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	result, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, nil, RunOptions{})
+	if err == nil {
+		t.Fatalf("Expected step use to fail")
+	}
+
+	st, err := state.Load(result.RunID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := st.Vars["local"]["TOKEN"]; got != "token" {
+		t.Errorf("Expected TOKEN to be saved, got %q", got)
+	}
+
+	if err := os.WriteFile(fixed, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	exec = New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	if _, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, nil, RunOptions{Steps: []string{"use"}, Resume: st}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "token\n" {
+		t.Errorf("Expected %q, got %q", "token\n", string(data))
+	}
+}
+
+func TestExecutePlan_Order(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name  string
+		order string
+		limit string
+		want  string
+	}{
+		{name: "inventory", order: "", want: "c\na\nb\n"},
+		{name: "sorted", order: "sorted", want: "a\nb\nc\n"},
+		{name: "reverse", order: "reverse", want: "b\na\nc\n"},
+		{name: "canary", order: "sorted", limit: "1", want: "a\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			file := &schema.File{
+				Jobs: map[string]schema.Job{
+					"record": {Local: true, Actions: []schema.Action{runAction(`echo "$HADES_HOST_NAME" >> ` + out)}},
+				},
+			}
+			plan := &schema.Plan{
+				Steps: []schema.Step{
+					{Name: "record", Job: "record", Targets: []string{"all"}, Parallelism: "1", Order: tt.order, Limit: tt.limit},
+				},
+			}
+
+			exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+			inv := staticInventory{{Name: "c"}, {Name: "a"}, {Name: "b"}}
+			if _, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, nil, RunOptions{}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, string(data))
+			}
+		})
+	}
+}
+
+func TestExecutePlan_BatchGates(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name     string
+		check    string
+		confirm  bool
+		answers  string
+		wantErr  bool
+		wantHost string
+		want     string
+	}{
+		{
+			name:  "check passes",
+			check: `echo "check $HADES_HOST_NAME" >> OUT`,
+			want:  "job a\ncheck a\njob b\ncheck b\njob c\ncheck c\n",
+		},
+		{
+			name:     "check fails",
+			check:    `test "$HADES_HOST_NAME" != b`,
+			wantErr:  true,
+			wantHost: "b",
+			want:     "job a\njob b\n",
+		},
+		{
+			name:    "confirmed",
+			confirm: true,
+			answers: "y\nyes\n",
+			want:    "job a\njob b\njob c\n",
+		},
+		{
+			name:    "declined",
+			confirm: true,
+			answers: "y\nn\n",
+			wantErr: true,
+			want:    "job a\njob b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out")
+			file := &schema.File{
+				Jobs: map[string]schema.Job{
+					"deploy": {Local: true, Actions: []schema.Action{runAction(`echo "job $HADES_HOST_NAME" >> ` + out)}},
+				},
+			}
+			step := schema.Step{
+				Name:                  "deploy",
+				Job:                   "deploy",
+				Targets:               []string{"all"},
+				Parallelism:           "1",
+				PauseBetweenBatches:   "10ms",
+				ConfirmBetweenBatches: tt.confirm,
+			}
+			if tt.check != "" {
+				step.BatchCheck = &schema.BatchCheck{Run: strings.ReplaceAll(tt.check, "OUT", out), Local: true}
+			}
+			plan := &schema.Plan{Steps: []schema.Step{step}}
+
+			exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+			exec.(*executor).stdin = bufio.NewReader(strings.NewReader(tt.answers))
+			inv := staticInventory{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+			result, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, nil, RunOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if result.FailedHost != tt.wantHost {
+				t.Errorf("Expected %q, got %q", tt.wantHost, result.FailedHost)
+			}
+
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, string(data))
+			}
+		})
+	}
+}
+
+func TestConfirm_AnswersGoToOnePrompt(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard).(*executor)
+	exec.stdin = bufio.NewReader(r)
+
+	// An abandoned prompt must not swallow the answer meant for the next one
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := exec.confirm(ctx, exec.stdout, "first?"); !errors.Is(err, ErrInterrupted) {
+		t.Fatalf("Expected ErrInterrupted, got %v", err)
+	}
+
+	go io.WriteString(w, "y\nn\ny\n")
+
+	// Concurrent prompts each get exactly one answer
+	answers := make(chan bool, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			approved, err := exec.confirm(context.Background(), exec.stdout, "next?")
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			answers <- approved
+		}()
+	}
+	approved := 0
+	for i := 0; i < 3; i++ {
+		if <-answers {
+			approved++
+		}
+	}
+	if approved != 2 {
+		t.Errorf("Expected 2 approvals, got %d", approved)
+	}
+}
+
+func TestConfirm_ReadsOnlyWhilePrompting(t *testing.T) {
+	r, w := io.Pipe()
+	defer r.Close()
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard).(*executor)
+	exec.stdin = bufio.NewReader(r)
+
+	go io.WriteString(w, "y\n")
+	if approved, err := exec.confirm(context.Background(), exec.stdout, "first?"); err != nil || !approved {
+		t.Fatalf("Expected approval, got %v (%v)", approved, err)
+	}
+
+	// Input typed after the prompt is left for others, e.g. a password prompt
+	written := make(chan struct{})
+	go func() {
+		io.WriteString(w, "secret\n")
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Error("Expected stdin not to be read between prompts")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{name: "lines", writes: []string{"  Job: a\n  Hosts: 1\n"}, want: "a |   Job: a\na |   Hosts: 1\n"},
+		{name: "blank lines", writes: []string{"\n  Status: done\n\n"}, want: "\na |   Status: done\n\n"},
+		{name: "split line", writes: []string{"Continue? ", "y\n", "next\n"}, want: "a | Continue? y\na | next\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := &prefixWriter{w: &buf, prefix: "a | "}
+			for _, s := range tt.writes {
+				n, err := io.WriteString(w, s)
+				if err != nil || n != len(s) {
+					t.Fatalf("Expected %d bytes written, got %d (%v)", len(s), n, err)
+				}
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecutePlan_Needs(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name       string
+		failB      bool
+		wantFailed bool
+		want       string
+	}{
+		{name: "concurrent", want: "a\nb\nc\n"},
+		{name: "failure stops dependents", failB: true, wantFailed: true, want: "a\nb\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			out := filepath.Join(dir, "out")
+			// Each of a and b waits for the other to start, so they only
+			// succeed when run concurrently
+			wait := func(self, other string) schema.Job {
+				cmd := "touch " + filepath.Join(dir, self) +
+					"; for i in $(seq 50); do test -f " + filepath.Join(dir, other) + " && break; sleep 0.1; done" +
+					"; test -f " + filepath.Join(dir, other) + " && echo " + self + " >> " + out
+				return schema.Job{Local: true, Actions: []schema.Action{runAction(cmd)}}
+			}
+			b := wait("b", "a")
+			if tt.failB {
+				b.Actions = append(b.Actions, runAction("false"))
+			}
+
+			file := &schema.File{
+				Jobs: map[string]schema.Job{
+					"a": wait("a", "b"),
+					"b": b,
+					"c": {Local: true, Actions: []schema.Action{runAction("echo c >> " + out)}},
+				},
+			}
+			plan := &schema.Plan{
+				Steps: []schema.Step{
+					{Name: "c", Job: "c", Targets: []string{"local"}, Needs: []string{"a", "b"}},
+					{Name: "a", Job: "a", Targets: []string{"local"}, Needs: []string{}},
+					{Name: "b", Job: "b", Targets: []string{"local"}, Needs: []string{}},
+				},
+			}
+
+			exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+			inv := staticInventory{{Name: "local"}}
+			result, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, nil, RunOptions{})
+			if tt.wantFailed {
+				if err == nil || result.FailedStep != "b" {
+					t.Errorf("Expected step %q to fail, got %+v", "b", result)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sortedLines(string(data)); got != sortedLines(tt.want) {
+				t.Errorf("Expected %q, got %q", sortedLines(tt.want), got)
+			}
+		})
+	}
+}
+
+func TestExecutePlan_PerHost(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	tests := []struct {
+		name        string
+		first       string // Command of the first step
+		concurrency string
+		maxFail     string
+		sorted      bool
+		want        string
+		wantFailed  []string
+	}{
+		{
+			// a only finishes the first step once b finished the second
+			name:   "pipelined",
+			first:  `if [ $HADES_HOST_NAME = a ]; then for i in $(seq 50); do test -f $DIR/b-second && break; sleep 0.1; done; test -f $DIR/b-second; fi`,
+			sorted: true,
+			want:   "a first\na second\nb first\nb second\n",
+		},
+		{
+			name:        "concurrency",
+			first:       "true",
+			concurrency: "1",
+			want:        "a first\na second\nb first\nb second\n",
+		},
+		{
+			name:       "host failure",
+			first:      `test $HADES_HOST_NAME != a`,
+			maxFail:    "1",
+			want:       "b first\nb second\n",
+			wantFailed: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			out := filepath.Join(dir, "out")
+			file := &schema.File{
+				Jobs: map[string]schema.Job{
+					"first": {Local: true, Actions: []schema.Action{
+						runAction(strings.ReplaceAll(tt.first, "$DIR", dir)),
+						runAction(`echo "$HADES_HOST_NAME first" >> ` + out),
+					}},
+					"second": {Local: true, Actions: []schema.Action{
+						runAction(`touch ` + dir + `/$HADES_HOST_NAME-second`),
+						runAction(`echo "$HADES_HOST_NAME second" >> ` + out),
+					}},
+				},
+			}
+			plan := &schema.Plan{
+				Strategy:    schema.StrategyPerHost,
+				Concurrency: tt.concurrency,
+				Steps: []schema.Step{
+					{Name: "first", Job: "first", Targets: []string{"all"}, MaxFail: tt.maxFail},
+					{Name: "second", Job: "second", Targets: []string{"all"}},
+				},
+			}
+
+			exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+			inv := staticInventory{{Name: "a"}, {Name: "b"}}
+			result, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, nil, RunOptions{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(result.FailedHosts, ",") != strings.Join(tt.wantFailed, ",") {
+				t.Errorf("Expected failed hosts %v, got %v", tt.wantFailed, result.FailedHosts)
+			}
+
+			data, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, want := string(data), tt.want
+			if tt.sorted {
+				got, want = sortedLines(got), sortedLines(want)
+			}
+			if got != want {
+				t.Errorf("Expected %q, got %q", want, got)
+			}
+
+			st, err := state.Load(result.RunID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range st.Steps {
+				if step.Status != state.StatusCompleted {
+					t.Errorf("Expected step %q to be %s, got %s", step.Name, state.StatusCompleted, step.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestExecutePlan_IncludeJob(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	out := filepath.Join(t.TempDir(), "out")
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"caddy": {
+				Local: true,
+				Env: map[string]schema.Env{
+					"VERSION": {},
+					"PORT":    {Default: "80"},
+				},
+				Actions: []schema.Action{
+					runAction(`echo "caddy $VERSION $PORT" >> ` + out),
+					{Job: &schema.ActionJob{Name: "reload"}},
+				},
+			},
+			"reload": {
+				Local:   true,
+				Actions: []schema.Action{runAction(`echo "reload $VERSION" >> ` + out)},
+			},
+			"deploy": {
+				Local: true,
+				Env:   map[string]schema.Env{"RELEASE": {Default: "2.7"}},
+				Actions: []schema.Action{
+					runAction(`echo "before" >> ` + out),
+					{Job: &schema.ActionJob{Name: "caddy", Env: map[string]string{"VERSION": "v${RELEASE}"}}},
+					runAction(`echo "after ${VERSION:-none}" >> ` + out),
+				},
+			},
+		},
+	}
+	plan := &schema.Plan{
+		Steps: []schema.Step{{Name: "deploy", Job: "deploy", Targets: []string{"local"}}},
+	}
+
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	inv := staticInventory{{Name: "local"}}
+	result, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, nil, RunOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "before\ncaddy v2.7 80\nreload v2.7\nafter none\n"
+	if string(data) != want {
+		t.Errorf("Expected %q, got %q", want, string(data))
+	}
+
+	logs, err := os.ReadFile(filepath.Join("logs", result.RunID, "test.local.out.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, delimiter := range []string{"JOB: deploy, ACTION: [1] job", "JOB: caddy, ACTION: [1.0] run", "JOB: reload, ACTION: [1.1.0] run", "JOB: deploy, ACTION: [2] run"} {
+		if !strings.Contains(string(logs), delimiter) {
+			t.Errorf("Expected log to contain %q, got %q", delimiter, string(logs))
+		}
+	}
+}
+
+func TestExecutePlan_IncludeJobExportEnv(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	out := filepath.Join(t.TempDir(), "out")
+	exportEnv := false
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"private": {
+				Local:     true,
+				ExportEnv: &exportEnv,
+				Actions:   []schema.Action{runAction(`echo "private $(printenv RELEASE || echo unset)" >> ` + out)},
+			},
+			"deploy": {
+				Local: true,
+				Env:   map[string]schema.Env{"RELEASE": {Default: "2.7"}},
+				Actions: []schema.Action{
+					{Job: &schema.ActionJob{Name: "private"}},
+					runAction(`echo "deploy $(printenv RELEASE)" >> ` + out),
+				},
+			},
+		},
+	}
+	plan := &schema.Plan{
+		Steps: []schema.Step{{Name: "deploy", Job: "deploy", Targets: []string{"local"}}},
+	}
+
+	exec := New(ssh.NewLocalClient(), io.Discard, io.Discard)
+	inv := staticInventory{{Name: "local"}}
+	if _, err := exec.ExecutePlan(context.Background(), file, plan, "test", inv, nil, nil, RunOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "private unset\ndeploy 2.7\n"
+	if string(data) != want {
+		t.Errorf("Expected %q, got %q", want, string(data))
+	}
+}
@@ -0,0 +1,75 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/SoftKiwiGames/hades/hades/actions"
+	"github.com/SoftKiwiGames/hades/hades/loader"
+	"github.com/SoftKiwiGames/hades/hades/logger"
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/types"
+	"github.com/wzshiming/ctc"
+)
+
+// includeJob runs the actions of an included job as [index.N], with the
+// included job's env, become and timeouts. The including job's env is
+// restored afterwards, so variables the included job registers stay in it.
+func (e *executor) includeJob(ctx context.Context, file *schema.File, include *schema.ActionJob, index string, runtime *types.Runtime, hostLogger *logger.Logger, vars *hostVars) error {
+	job, err := e.loadJob(file, include.Name)
+	if err != nil {
+		return err
+	}
+
+	env, hostEnv := runtime.Env, runtime.Host.Env
+	defer func() {
+		runtime.Env, runtime.Host.Env = env, hostEnv
+	}()
+
+	runtime.Env = includeEnv(job, include, env)
+	if loader.ExportEnv(job) {
+		runtime.Host.Env = runtime.Env
+	} else {
+		// The including job's exported env doesn't leak into this one
+		runtime.Host.Env = nil
+	}
+
+	if job.Guard != nil {
+		runtime.Host.Become = loader.JobBecome(job)
+		result, err := actions.EvaluateGuard(ctx, job.Guard, runtime)
+		if err != nil {
+			return fmt.Errorf("job %q: guard evaluation failed: %w", include.Name, err)
+		}
+		if !result.Pass {
+			fmt.Fprintf(e.stdout, "[%s] %s◇%s Job %q: skipped (guard failed)\n", runtime.Host.Name, ctc.ForegroundBlue, ctc.Reset, include.Name)
+			return nil
+		}
+	}
+
+	return e.runActions(ctx, file, job, include.Name, index+".", runtime, hostLogger, vars)
+}
+
+// includeEnv returns the env an included job runs with: the including job's
+// env and the action's overrides (expanded against it) over the job's defaults
+func includeEnv(job *schema.Job, include *schema.ActionJob, env map[string]string) map[string]string {
+	provided := maps.Clone(env)
+	for name, value := range include.Env {
+		provided[name] = actions.ExpandEnvVars(value, env)
+	}
+	return loader.MergeEnv(job, provided)
+}
+
+// dryRunInclude lists the actions of an included job with its env
+func (e *executor) dryRunInclude(ctx context.Context, file *schema.File, include *schema.ActionJob, runtime *types.Runtime, indent string) error {
+	job, err := e.loadJob(file, include.Name)
+	if err != nil {
+		return err
+	}
+
+	env := runtime.Env
+	defer func() { runtime.Env = env }()
+	runtime.Env = includeEnv(job, include, env)
+
+	return e.dryRunActions(ctx, file, job, runtime, indent)
+}
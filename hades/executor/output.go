@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/SoftKiwiGames/hades/hades/ui"
+)
+
+// stepOutput is where a step prints its own lines (header, batches, status).
+// Output of its hosts' jobs goes to the executor's writers as usual.
+type stepOutput struct {
+	stdout io.Writer
+	stderr io.Writer
+	ui     *ui.Output
+}
+
+// stepOutput returns the output of the named step. A step that may run
+// alongside other steps prefixes its lines with its name, so they can be told
+// apart when they interleave.
+func (e *executor) stepOutput(name string, concurrent bool) *stepOutput {
+	if !concurrent {
+		return &stepOutput{stdout: e.stdout, stderr: e.stderr, ui: e.ui}
+	}
+
+	prefix := name + " | "
+	stdout := &prefixWriter{w: e.stdout, prefix: prefix}
+	stderr := &prefixWriter{w: e.stderr, prefix: prefix}
+	return &stepOutput{stdout: stdout, stderr: stderr, ui: ui.NewOutput(stdout, stderr)}
+}
+
+// prefixWriter starts every non-empty line written to w with prefix. Each
+// Write goes to w in a single call, so lines of different steps don't mix.
+// It is not safe for concurrent use.
+type prefixWriter struct {
+	w       io.Writer
+	prefix  string
+	midLine bool // The last write didn't end with a newline
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if !p.midLine && line[0] != '\n' {
+			buf.WriteString(p.prefix)
+		}
+		buf.Write(line)
+		p.midLine = line[len(line)-1] != '\n'
+	}
+
+	if _, err := p.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
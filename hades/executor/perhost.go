@@ -0,0 +1,254 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/SoftKiwiGames/hades/hades/rollout"
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+	"github.com/SoftKiwiGames/hades/hades/state"
+)
+
+// hostStep is a plan step prepared for per-host runs
+type hostStep struct {
+	idx        int
+	step       schema.Step
+	job        *schema.Job
+	env        map[string]string
+	targetName string
+	hosts      map[string]bool // Hosts that run the step
+	hostCount  int
+	maxFail    int
+	failures   []hostFailure
+	progress   *state.Step
+	startedOn  []ssh.Host
+}
+
+// runPerHost runs the plan with strategy per_host: each host goes through its
+// steps on its own, without waiting for other hosts, and at most concurrency
+// hosts run at once. A host that fails a step skips its remaining steps; once
+// a step fails on more hosts than its max_fail allows, no host starts another
+// step. Returns the same as runSteps.
+func (e *executor) runPerHost(ctx context.Context, run *planRun, opts RunOptions) ([][]ssh.Host, string, error) {
+	touched := make([][]ssh.Host, len(run.plan.Steps))
+
+	steps, hosts, err := e.prepareHostSteps(run, opts)
+	if err != nil {
+		return touched, "", err
+	}
+
+	concurrency, err := rollout.ParseConcurrency(run.plan.Concurrency, len(hosts))
+	if err != nil {
+		return touched, "", &stepFailure{err: fmt.Errorf("invalid concurrency: %w", err)}
+	}
+	e.ui.Info("Strategy: %s (%d hosts, %d at a time)", schema.StrategyPerHost, len(hosts), concurrency)
+
+	var mu sync.Mutex // Guards steps and the fields below
+	var stoppedAt string
+	var stopErr error
+	var interruptedHosts []string
+	stop := func(step string, err error) {
+		if stopErr == nil {
+			stoppedAt = step
+			stopErr = err
+		}
+	}
+	stopped := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return stopErr != nil
+	}
+
+	// pipeline runs the host's steps in plan order
+	pipeline := func(host ssh.Host) {
+		for _, hs := range steps {
+			if !hs.hosts[host.Name] {
+				continue
+			}
+			if stopped() {
+				return
+			}
+			if e.stopping(ctx) {
+				mu.Lock()
+				stop(hs.step.Name, fmt.Errorf("%w before step %q", ErrInterrupted, hs.step.Name))
+				mu.Unlock()
+				return
+			}
+
+			mu.Lock()
+			hs.startedOn = append(hs.startedOn, host)
+			mu.Unlock()
+			e.updateState(run, func() { hs.progress.Status = state.StatusRunning })
+
+			fmt.Fprintf(e.stdout, "[%s] Step %d/%d: %s\n", host.Name, hs.idx+1, len(run.plan.Steps), hs.step.Name)
+			batch := []ssh.Host{host}
+			failures, err := e.executeBatch(ctx, run.file, hs.job, hs.step.Job, run.result.RunID, run.planName, hs.targetName, batch, hs.env, run.artifactMgr, run.registryMgr, run.vars)
+			e.recordBatch(run, hs.progress, batch, failures, err)
+
+			if errors.Is(err, ErrInterrupted) {
+				mu.Lock()
+				interruptedHosts = append(interruptedHosts, host.Name)
+				stop(hs.step.Name, err)
+				mu.Unlock()
+				return
+			}
+			if len(failures) > 0 {
+				run.mu.Lock()
+				run.failedHosts[host.Name] = true
+				run.result.FailedHosts = append(run.result.FailedHosts, host.Name)
+				run.mu.Unlock()
+				e.skipRemaining(run, steps, hs, host.Name)
+
+				mu.Lock()
+				hs.failures = append(hs.failures, failures...)
+				if len(hs.failures) > hs.maxFail {
+					stop(hs.step.Name, &stepFailure{
+						step: hs.step.Name,
+						host: hs.failures[0].host,
+						err:  stepError(hs.failures, hs.maxFail, hs.step.MaxFail, hs.hostCount),
+					})
+				}
+				mu.Unlock()
+				return
+			}
+		}
+	}
+
+	// Hosts start in order as slots free up
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		slots <- struct{}{}
+		if stopped() {
+			<-slots
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			pipeline(host)
+		}()
+	}
+	wg.Wait()
+
+	for _, hs := range steps {
+		touched[hs.idx] = hs.startedOn
+		e.updateState(run, func() { hs.progress.Status = hostStepStatus(hs, stopErr) })
+	}
+
+	// Report every host an interrupt cut short, not only the first
+	if errors.Is(stopErr, ErrInterrupted) && len(interruptedHosts) > 0 {
+		sort.Strings(interruptedHosts)
+		stopErr = &interruptedError{hosts: interruptedHosts}
+	}
+	return touched, stoppedAt, stopErr
+}
+
+// prepareHostSteps resolves the hosts of every selected step up front and
+// returns the steps with every host that runs any of them, in first-seen order
+func (e *executor) prepareHostSteps(run *planRun, opts RunOptions) ([]*hostStep, []ssh.Host, error) {
+	var steps []*hostStep
+	var hosts []ssh.Host
+	seen := make(map[string]bool)
+
+	for i, step := range run.plan.Steps {
+		if len(opts.Steps) > 0 && !slices.Contains(opts.Steps, step.Name) {
+			continue
+		}
+
+		// Determine which targets to use: CLI overrides YAML
+		stepTargets := step.Targets
+		if len(run.targets) > 0 {
+			stepTargets = run.targets
+		}
+
+		stepState := run.state.Step(step.Name)
+		var stepHosts []ssh.Host
+		var err error
+		if len(stepState.Hosts) > 0 {
+			// Same hosts as before, except those that already completed the step
+			stepHosts, err = resumeHosts(run.inv, stepState)
+			e.updateState(run, func() { stepState.Failed = nil })
+		} else {
+			// Hosts that failed a completed step of a resumed run are left out
+			stepHosts, err = e.resolveHosts(run, step, stepTargets, true)
+			if err == nil {
+				stepHosts, err = limitHosts(step, stepHosts)
+			}
+			e.updateState(run, func() {
+				for _, host := range stepHosts {
+					stepState.Hosts = append(stepState.Hosts, host.Name)
+				}
+			})
+		}
+		if err != nil {
+			return nil, nil, &stepFailure{step: step.Name, err: err}
+		}
+
+		job, _, env, err := e.stepJob(run, step, nil)
+		if err != nil {
+			return nil, nil, &stepFailure{step: step.Name, err: err}
+		}
+		maxFail, err := rollout.ParseMaxFail(step.MaxFail, len(stepState.Hosts))
+		if err != nil {
+			return nil, nil, &stepFailure{step: step.Name, err: fmt.Errorf("invalid max_fail: %w", err)}
+		}
+
+		hs := &hostStep{
+			idx:        i,
+			step:       step,
+			job:        job,
+			env:        env,
+			targetName: stepTargets[0],
+			hosts:      make(map[string]bool),
+			hostCount:  len(stepState.Hosts),
+			maxFail:    maxFail,
+			progress:   stepState,
+		}
+		for _, host := range stepHosts {
+			hs.hosts[host.Name] = true
+			if !seen[host.Name] {
+				seen[host.Name] = true
+				hosts = append(hosts, host)
+			}
+		}
+		steps = append(steps, hs)
+	}
+	return steps, hosts, nil
+}
+
+// skipRemaining drops a host that failed step hs from its later steps, as if
+// they had been resolved without it
+func (e *executor) skipRemaining(run *planRun, steps []*hostStep, hs *hostStep, host string) {
+	e.updateState(run, func() {
+		for _, later := range steps {
+			if later.idx <= hs.idx || !later.hosts[host] {
+				continue
+			}
+			later.progress.Hosts = slices.DeleteFunc(later.progress.Hosts, func(name string) bool { return name == host })
+		}
+	})
+}
+
+// hostStepStatus returns the status of a step once every host stopped
+func hostStepStatus(hs *hostStep, stopErr error) string {
+	var failure *stepFailure
+	switch {
+	case errors.As(stopErr, &failure) && failure.step == hs.step.Name:
+		return state.StatusFailed
+	case len(hs.progress.Completed)+len(hs.progress.Failed) == len(hs.progress.Hosts):
+		return state.StatusCompleted
+	case len(hs.startedOn) > 0 && errors.Is(stopErr, ErrInterrupted):
+		return state.StatusInterrupted
+	case len(hs.startedOn) > 0:
+		return state.StatusFailed
+	default:
+		return state.StatusPending
+	}
+}
@@ -0,0 +1,79 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/SoftKiwiGames/hades/hades/inventory"
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+	"github.com/SoftKiwiGames/hades/hades/state"
+)
+
+// saveState writes the run's progress. A failed write doesn't stop the run,
+// it only makes it impossible to resume.
+func (e *executor) saveState(run *planRun) {
+	e.updateState(run, func() {})
+}
+
+// updateState changes the run's progress with update and saves it, along
+// with the variables registered so far
+func (e *executor) updateState(run *planRun, update func()) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	update()
+	run.state.Vars = run.vars.snapshot()
+	if err := run.state.Save(); err != nil {
+		e.ui.Warning("Failed to save run state: %v", err)
+	}
+}
+
+// recordBatch adds a finished batch to a step's progress
+func (e *executor) recordBatch(run *planRun, step *state.Step, batch []ssh.Host, failures []hostFailure, err error) {
+	if step == nil {
+		return
+	}
+
+	unfinished := make(map[string]bool)
+	var failed []string
+	for _, failure := range failures {
+		unfinished[failure.host] = true
+		failed = append(failed, failure.host)
+	}
+	var ie *interruptedError
+	if errors.As(err, &ie) {
+		for _, host := range ie.hosts {
+			unfinished[host] = true
+		}
+	}
+
+	e.updateState(run, func() {
+		step.Failed = append(step.Failed, failed...)
+		for _, host := range batch {
+			if !unfinished[host.Name] {
+				step.Completed = append(step.Completed, host.Name)
+			}
+		}
+	})
+}
+
+// resumeHosts returns the hosts a resumed step ran on that have not completed it
+func resumeHosts(inv inventory.Inventory, step *state.Step) ([]ssh.Host, error) {
+	byName := make(map[string]ssh.Host)
+	for _, host := range inv.AllHosts() {
+		byName[host.Name] = host
+	}
+
+	var hosts []ssh.Host
+	for _, name := range step.Hosts {
+		if step.Done(name) {
+			continue
+		}
+		host, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("host %q from the resumed run is no longer in the inventory", name)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/SoftKiwiGames/hades/hades/loader"
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+	"github.com/SoftKiwiGames/hades/hades/state"
+)
+
+// stepOutcome is a plan step that finished running
+type stepOutcome struct {
+	idx   int
+	hosts []ssh.Host
+	err   error
+}
+
+// runSteps runs the selected plan steps, each as soon as the steps it needs
+// have completed, so independent steps run concurrently. Returns the hosts
+// each step was started on (indexed like plan.Steps) and, if the run stopped
+// early, the step that stopped it with its error.
+func (e *executor) runSteps(ctx context.Context, run *planRun, opts RunOptions) ([][]ssh.Host, string, error) {
+	steps := run.plan.Steps
+	touched := make([][]ssh.Host, len(steps))
+	levels, err := loader.StepLevels(run.plan)
+	if err != nil {
+		return touched, "", &stepFailure{err: err}
+	}
+	// Lines of steps that may run at the same time carry the step name
+	concurrent := slices.ContainsFunc(levels, func(level []string) bool { return len(level) > 1 })
+	needs := loader.StepNeeds(run.plan)
+
+	started := make([]bool, len(steps))
+	completed := make([]bool, len(steps))
+	done := make(chan stepOutcome)
+	running := 0
+
+	var stoppedAt string
+	var stopErr error
+
+	// start runs every step whose needs have completed; steps left out of the
+	// selection complete at once, which may make others ready
+	start := func() {
+		for changed := true; changed && stopErr == nil; {
+			changed = false
+			for i, step := range steps {
+				if started[i] || slices.ContainsFunc(needs[i], func(need int) bool { return !completed[need] }) {
+					continue
+				}
+				started[i] = true
+				changed = true
+
+				if len(opts.Steps) > 0 && !slices.Contains(opts.Steps, step.Name) {
+					completed[i] = true
+					continue
+				}
+
+				if e.stopping(ctx) {
+					stoppedAt = step.Name
+					stopErr = fmt.Errorf("%w before step %q", ErrInterrupted, step.Name)
+					return
+				}
+
+				running++
+				go func() {
+					hosts, err := e.runPlanStep(ctx, run, e.stepOutput(step.Name, concurrent), i, step)
+					done <- stepOutcome{idx: i, hosts: hosts, err: err}
+				}()
+			}
+		}
+	}
+
+	start()
+	for running > 0 {
+		outcome := <-done
+		running--
+		touched[outcome.idx] = outcome.hosts
+
+		if outcome.err != nil {
+			// Steps already running finish, but no new ones start
+			if stopErr == nil {
+				stoppedAt = steps[outcome.idx].Name
+				stopErr = outcome.err
+			}
+			continue
+		}
+		completed[outcome.idx] = true
+		start()
+	}
+
+	return touched, stoppedAt, stopErr
+}
+
+// runPlanStep runs plan step i on its hosts, or on the hosts that have not
+// completed it yet when resuming, and records its progress
+func (e *executor) runPlanStep(ctx context.Context, run *planRun, out *stepOutput, i int, step schema.Step) ([]ssh.Host, error) {
+	// Determine which targets to use: CLI overrides YAML
+	stepTargets := step.Targets
+	if len(run.targets) > 0 {
+		stepTargets = run.targets
+	}
+
+	stepState := run.state.Step(step.Name)
+	setStatus := func(status string) {
+		e.updateState(run, func() { stepState.Status = status })
+	}
+
+	var hosts []ssh.Host
+	var err error
+	resumed := len(stepState.Hosts) > 0
+	if resumed {
+		// Same hosts as before, except those that already completed the step
+		hosts, err = resumeHosts(run.inv, stepState)
+		e.updateState(run, func() { stepState.Failed = nil })
+	} else {
+		// Hosts that failed an earlier step are left out
+		hosts, err = e.resolveHosts(run, step, stepTargets, true)
+		if err == nil {
+			// Apply limit if specified (canary)
+			hosts, err = limitHosts(step, hosts)
+		}
+		e.updateState(run, func() {
+			for _, host := range hosts {
+				stepState.Hosts = append(stepState.Hosts, host.Name)
+			}
+		})
+	}
+	if err != nil {
+		setStatus(state.StatusFailed)
+		return nil, &stepFailure{step: step.Name, err: err}
+	}
+
+	out.ui.StepProgress(i+1, len(run.plan.Steps), step.Name)
+	if resumed {
+		if len(hosts) == 0 {
+			out.ui.Info("  All hosts already completed this step")
+			setStatus(state.StatusCompleted)
+			return nil, nil
+		}
+		out.ui.Info("  Resuming: %d of %d host(s) already completed", len(stepState.Completed), len(stepState.Hosts))
+	}
+
+	setStatus(state.StatusRunning)
+	stepHosts, err := e.executeStep(ctx, run, out, step, hosts, stepTargets[0], nil, stepState)
+	switch {
+	case errors.Is(err, ErrInterrupted):
+		setStatus(state.StatusInterrupted)
+	case err != nil:
+		setStatus(state.StatusFailed)
+	default:
+		setStatus(state.StatusCompleted)
+	}
+	return stepHosts, err
+}
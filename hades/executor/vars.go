@@ -0,0 +1,94 @@
+package executor
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/SoftKiwiGames/hades/hades/loader"
+)
+
+// hostVars holds variables registered with register_scope: plan, per host,
+// so later steps of the plan on the same host can use them
+type hostVars struct {
+	mu   sync.Mutex
+	vars map[string]map[string]string // host name -> name -> value
+}
+
+// newHostVars returns the variables of a run, starting from those saved by
+// the run being resumed (nil for a new run)
+func newHostVars(saved map[string]map[string]string) *hostVars {
+	v := &hostVars{vars: make(map[string]map[string]string)}
+	for host, vars := range saved {
+		v.vars[host] = maps.Clone(vars)
+	}
+	return v
+}
+
+// snapshot returns a copy of every host's variables, for the run's state
+func (v *hostVars) snapshot() map[string]map[string]string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.vars) == 0 {
+		return nil
+	}
+	snap := make(map[string]map[string]string, len(v.vars))
+	for host, vars := range v.vars {
+		snap[host] = maps.Clone(vars)
+	}
+	return snap
+}
+
+// set stores the values registered as name (with its _STDERR and _RC) from env
+func (v *hostVars) set(host string, name string, env map[string]string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.vars[host] == nil {
+		v.vars[host] = make(map[string]string)
+	}
+	for _, key := range []string{name, name + "_STDERR", name + "_RC"} {
+		if value, ok := env[key]; ok {
+			v.vars[host][key] = value
+		}
+	}
+}
+
+// apply returns env with the host's registered variables added. Variables
+// already in env (job env, step env, CLI) take precedence.
+func (v *hostVars) apply(host string, env map[string]string) map[string]string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.vars[host]) == 0 {
+		return env
+	}
+
+	merged := make(map[string]string, len(env)+len(v.vars[host]))
+	for key, value := range v.vars[host] {
+		merged[key] = value
+	}
+	for key, value := range env {
+		merged[key] = value
+	}
+	return merged
+}
+
+// warnSkippedRegisters warns about plan-scope variables that steps skipped by
+// --start-at-step or --only-steps would have registered. A resumed run has
+// them from its state instead.
+func (e *executor) warnSkippedRegisters(run *planRun, opts RunOptions) {
+	if opts.Resume != nil || len(opts.Steps) == 0 {
+		return
+	}
+	for _, step := range run.plan.Steps {
+		if slices.Contains(opts.Steps, step.Name) {
+			continue
+		}
+		if names := loader.JobRegisters(run.file, step.Job); len(names) > 0 {
+			e.ui.Warning("Skipped step %q registers %s; later steps won't see them (use --resume to keep them)", step.Name, strings.Join(names, ", "))
+		}
+	}
+}
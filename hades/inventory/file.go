@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/SoftKiwiGames/hades/hades/ssh"
 	"github.com/SoftKiwiGames/hades/hades/utils"
@@ -21,13 +24,121 @@ type inventoryFile struct {
 	Hosts          map[string]hostDef  `yaml:"hosts"`
 	Targets        map[string][]string `yaml:"targets"`
 	HostsProviders []Provider          `yaml:"hosts.providers"`
+	SSHConfigFile  string              `yaml:"ssh_config_file"` // Default ssh_config for hosts in this file
 }
 
 type hostDef struct {
-	Addr         string `yaml:"addr"`
-	User         string `yaml:"user"`
-	IdentityFile string `yaml:"identity_file"`
-	Port         int    `yaml:"port"`
+	Addr            string   `yaml:"addr"`
+	User            string   `yaml:"user"`
+	IdentityFile    string   `yaml:"identity_file"`
+	Port            int      `yaml:"port"`
+	HostKeyChecking string   `yaml:"host_key_checking"`
+	KnownHosts      string   `yaml:"known_hosts"`
+	Fingerprints    []string `yaml:"fingerprints"`
+	CertificateFile string   `yaml:"certificate_file"`
+	Auth            []string `yaml:"auth"`
+	Jump            string   `yaml:"jump"`
+	SSHConfig       string   `yaml:"ssh_config"`      // ssh_config alias to read connection details from
+	SSHConfigFile   string   `yaml:"ssh_config_file"` // Defaults to ~/.ssh/config
+	ConnectTimeout  string   `yaml:"connect_timeout"`
+	KeepAlive       string   `yaml:"keepalive_interval"`
+
+	jumpHost *ssh.Host // Bastion from ssh_config ProxyJump
+}
+
+// toHost converts a host definition into an ssh.Host, filling missing
+// fields from ssh_config and expanding paths
+func (h hostDef) toHost(name string, sshConfigFile string) (ssh.Host, error) {
+	h, err := h.applySSHConfig(name, sshConfigFile)
+	if err != nil {
+		return ssh.Host{}, err
+	}
+
+	keyPath, err := utils.ExpandPath(h.IdentityFile)
+	if err != nil {
+		return ssh.Host{}, fmt.Errorf("failed to expand identity_file: %w", err)
+	}
+
+	knownHosts, err := utils.ExpandPath(h.KnownHosts)
+	if err != nil {
+		return ssh.Host{}, fmt.Errorf("failed to expand known_hosts: %w", err)
+	}
+
+	if !ssh.ValidHostKeyPolicy(h.HostKeyChecking) {
+		return ssh.Host{}, fmt.Errorf("invalid host_key_checking %q (expected strict, tofu or off)", h.HostKeyChecking)
+	}
+
+	certPath, err := utils.ExpandPath(h.CertificateFile)
+	if err != nil {
+		return ssh.Host{}, fmt.Errorf("failed to expand certificate_file: %w", err)
+	}
+
+	if err := validateAuthMethods(h.Auth); err != nil {
+		return ssh.Host{}, err
+	}
+
+	connectTimeout, err := parseDurationSetting("connect_timeout", h.ConnectTimeout, ssh.DefaultConnectTimeout)
+	if err != nil {
+		return ssh.Host{}, err
+	}
+
+	keepAlive, err := parseDurationSetting("keepalive_interval", h.KeepAlive, ssh.DefaultKeepAliveInterval)
+	if err != nil {
+		return ssh.Host{}, err
+	}
+
+	host := ssh.Host{
+		Name:          name,
+		Address:       h.Addr,
+		User:          h.User,
+		KeyPath:       keyPath,
+		Port:          h.Port,
+		HostKeyPolicy: h.HostKeyChecking,
+		KnownHosts:    knownHosts,
+		Fingerprints:  h.Fingerprints,
+		CertPath:      certPath,
+		AuthMethods:   h.Auth,
+		Jump:          h.jumpHost,
+
+		ConnectTimeout: connectTimeout,
+		KeepAlive:      keepAlive,
+	}
+
+	// Bastions from ssh_config verify, authenticate and time out like the host itself
+	for jump := host.Jump; jump != nil; jump = jump.Jump {
+		jump.HostKeyPolicy = host.HostKeyPolicy
+		jump.KnownHosts = host.KnownHosts
+		jump.AuthMethods = host.AuthMethods
+		jump.ConnectTimeout = host.ConnectTimeout
+		jump.KeepAlive = host.KeepAlive
+	}
+
+	return host, nil
+}
+
+// parseDurationSetting parses an optional duration field; empty means def, 0 disables
+func parseDurationSetting(field string, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", field, value, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must not be negative", field, value)
+	}
+	return d, nil
+}
+
+// validateAuthMethods checks that every listed auth method is known
+func validateAuthMethods(methods []string) error {
+	for _, method := range methods {
+		if !ssh.ValidAuthMethod(method) {
+			return fmt.Errorf("invalid auth method %q (expected agent, cert or key)", method)
+		}
+	}
+	return nil
 }
 
 func LoadFile(path string) (Inventory, error) {
@@ -42,17 +153,15 @@ func LoadFile(path string) (Inventory, error) {
 	}
 
 	hostMap := make(map[string]ssh.Host)
+	jumps := make(map[string]string)
 	for name, h := range file.Hosts {
-		keyPath, err := utils.ExpandPath(h.IdentityFile)
+		host, err := h.toHost(name, file.SSHConfigFile)
 		if err != nil {
-			return nil, fmt.Errorf("failed to expand identity_file for host %q: %w", name, err)
+			return nil, fmt.Errorf("host %q: %w", name, err)
 		}
-		hostMap[name] = ssh.Host{
-			Name:    name,
-			Address: h.Addr,
-			User:    h.User,
-			KeyPath: keyPath,
-			Port:    h.Port,
+		hostMap[name] = host
+		if h.Jump != "" {
+			jumps[name] = h.Jump
 		}
 	}
 
@@ -63,17 +172,22 @@ func LoadFile(path string) (Inventory, error) {
 
 	var dynamicHosts []ssh.Host
 	if len(file.HostsProviders) > 0 {
-		dyn, err := resolveProviders(context.Background(), file.HostsProviders, hostMap, targets)
+		dyn, err := resolveProviders(context.Background(), file.HostsProviders, hostMap, targets, jumps)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve providers: %w", err)
 		}
 		dynamicHosts = dyn
 	}
 
+	if err := linkJumps(hostMap, jumps, dynamicHosts); err != nil {
+		return nil, err
+	}
+
 	hosts := make([]ssh.Host, 0, len(hostMap))
 	for _, h := range hostMap {
 		hosts = append(hosts, h)
 	}
+	sortHosts(hosts)
 
 	return &fileInventory{
 		hosts:        hosts,
@@ -87,6 +201,7 @@ func LoadFile(path string) (Inventory, error) {
 func LoadDirectory(rootPath string) (Inventory, error) {
 	allHosts := make(map[string]ssh.Host)
 	allTargets := make(map[string][]string)
+	allJumps := make(map[string]string)
 	var allProviders []Provider
 
 	err := filepath.WalkDir(rootPath, func(path string, d os.DirEntry, err error) error {
@@ -120,16 +235,13 @@ func LoadDirectory(rootPath string) (Inventory, error) {
 			if _, exists := allHosts[name]; exists {
 				return fmt.Errorf("duplicate host %q found in %s", name, path)
 			}
-			keyPath, err := utils.ExpandPath(h.IdentityFile)
+			host, err := h.toHost(name, file.SSHConfigFile)
 			if err != nil {
-				return fmt.Errorf("failed to expand identity_file for host %q in %s: %w", name, path, err)
+				return fmt.Errorf("host %q in %s: %w", name, path, err)
 			}
-			allHosts[name] = ssh.Host{
-				Name:    name,
-				Address: h.Addr,
-				User:    h.User,
-				KeyPath: keyPath,
-				Port:    h.Port,
+			allHosts[name] = host
+			if h.Jump != "" {
+				allJumps[name] = h.Jump
 			}
 		}
 
@@ -153,18 +265,23 @@ func LoadDirectory(rootPath string) (Inventory, error) {
 
 	var dynamicHosts []ssh.Host
 	if len(allProviders) > 0 {
-		dyn, err := resolveProviders(context.Background(), allProviders, allHosts, allTargets)
+		dyn, err := resolveProviders(context.Background(), allProviders, allHosts, allTargets, allJumps)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve providers: %w", err)
 		}
 		dynamicHosts = dyn
 	}
 
+	if err := linkJumps(allHosts, allJumps, dynamicHosts); err != nil {
+		return nil, err
+	}
+
 	// Convert map to slice for hosts
 	hosts := make([]ssh.Host, 0, len(allHosts))
 	for _, h := range allHosts {
 		hosts = append(hosts, h)
 	}
+	sortHosts(hosts)
 
 	return &fileInventory{
 		hosts:        hosts,
@@ -173,6 +290,13 @@ func LoadDirectory(rootPath string) (Inventory, error) {
 	}, nil
 }
 
+// sortHosts sorts hosts by name, as map iteration leaves them in random order
+func sortHosts(hosts []ssh.Host) {
+	slices.SortFunc(hosts, func(a, b ssh.Host) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
 func (f *fileInventory) ResolveTarget(name string) ([]ssh.Host, error) {
 	// Build map of hosts by name for quick lookup
 	hostMap := make(map[string]ssh.Host)
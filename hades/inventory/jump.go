@@ -0,0 +1,69 @@
+package inventory
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+)
+
+// resolveJumps links every host to its bastion (by inventory host name).
+// Chains are followed recursively; cycles and unknown hosts are errors.
+func resolveJumps(hosts map[string]ssh.Host, jumps map[string]string) error {
+	resolved := make(map[string]*ssh.Host)
+
+	var resolve func(name string, chain []string) (*ssh.Host, error)
+	resolve = func(name string, chain []string) (*ssh.Host, error) {
+		if host, ok := resolved[name]; ok {
+			return host, nil
+		}
+
+		for _, seen := range chain {
+			if seen == name {
+				return nil, fmt.Errorf("jump cycle: %s -> %s", strings.Join(chain, " -> "), name)
+			}
+		}
+
+		host, ok := hosts[name]
+		if !ok {
+			return nil, fmt.Errorf("jump host %q not defined", name)
+		}
+
+		if jumpName := jumps[name]; jumpName != "" {
+			jump, err := resolve(jumpName, append(chain, name))
+			if err != nil {
+				return nil, err
+			}
+			host.Jump = jump
+		}
+
+		resolved[name] = &host
+		return &host, nil
+	}
+
+	for name := range jumps {
+		host, err := resolve(name, nil)
+		if err != nil {
+			return fmt.Errorf("host %q: %w", name, err)
+		}
+		hosts[name] = *host
+	}
+
+	return nil
+}
+
+// linkJumps resolves jumps and refreshes the dynamic host copies with their bastions
+func linkJumps(hosts map[string]ssh.Host, jumps map[string]string, dynamic []ssh.Host) error {
+	if len(jumps) == 0 {
+		return nil
+	}
+
+	if err := resolveJumps(hosts, jumps); err != nil {
+		return err
+	}
+
+	for i, h := range dynamic {
+		dynamic[i] = hosts[h.Name]
+	}
+	return nil
+}
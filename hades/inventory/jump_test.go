@@ -0,0 +1,58 @@
+package inventory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+)
+
+func TestResolveJumps_Chain(t *testing.T) {
+	hosts := map[string]ssh.Host{
+		"edge":    {Name: "edge", Address: "203.0.113.1"},
+		"bastion": {Name: "bastion", Address: "10.0.0.1"},
+		"app-1":   {Name: "app-1", Address: "10.0.1.5"},
+	}
+	jumps := map[string]string{
+		"app-1":   "bastion",
+		"bastion": "edge",
+	}
+
+	if err := resolveJumps(hosts, jumps); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	app := hosts["app-1"]
+	if app.Jump == nil || app.Jump.Name != "bastion" {
+		t.Fatalf("Expected app-1 to jump via bastion, got %+v", app.Jump)
+	}
+	if app.Jump.Jump == nil || app.Jump.Jump.Name != "edge" {
+		t.Errorf("Expected bastion to jump via edge, got %+v", app.Jump.Jump)
+	}
+	if hosts["edge"].Jump != nil {
+		t.Error("Expected edge to be dialed directly")
+	}
+}
+
+func TestResolveJumps_Cycle(t *testing.T) {
+	hosts := map[string]ssh.Host{
+		"a": {Name: "a"},
+		"b": {Name: "b"},
+	}
+	jumps := map[string]string{"a": "b", "b": "a"}
+
+	err := resolveJumps(hosts, jumps)
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Expected jump cycle error, got %v", err)
+	}
+}
+
+func TestResolveJumps_UnknownHost(t *testing.T) {
+	hosts := map[string]ssh.Host{"a": {Name: "a"}}
+	jumps := map[string]string{"a": "missing"}
+
+	err := resolveJumps(hosts, jumps)
+	if err == nil || !strings.Contains(err.Error(), `"missing" not defined`) {
+		t.Errorf("Expected unknown jump host error, got %v", err)
+	}
+}
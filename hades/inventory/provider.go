@@ -9,7 +9,19 @@ type Provider struct {
 }
 
 type ProviderSSH struct {
-	User         string `yaml:"user"`
-	Port         int    `yaml:"port"`
-	IdentityFile string `yaml:"identity_file"`
+	User            string   `yaml:"user"`
+	Port            int      `yaml:"port"`
+	IdentityFile    string   `yaml:"identity_file"`
+	HostKeyChecking string   `yaml:"host_key_checking"`
+	KnownHosts      string   `yaml:"known_hosts"`
+	Fingerprints    []string `yaml:"fingerprints"`
+	CertificateFile string   `yaml:"certificate_file"`
+	Auth            []string `yaml:"auth"`
+	Jump            string   `yaml:"jump"`
+	UsePrivateIP    bool     `yaml:"use_private_ip"`
+	ConnectTimeout  string   `yaml:"connect_timeout"`
+	KeepAlive       string   `yaml:"keepalive_interval"`
+
+	// HostFingerprints pins fingerprints per instance name (overrides Fingerprints)
+	HostFingerprints map[string][]string `yaml:"host_fingerprints"`
 }
@@ -3,6 +3,8 @@ package inventory
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/SoftKiwiGames/hades/hades/cloud"
 	"github.com/SoftKiwiGames/hades/hades/selector"
@@ -10,7 +12,7 @@ import (
 	"github.com/SoftKiwiGames/hades/hades/utils"
 )
 
-func resolveProviders(ctx context.Context, providers []Provider, hosts map[string]ssh.Host, targets map[string][]string) ([]ssh.Host, error) {
+func resolveProviders(ctx context.Context, providers []Provider, hosts map[string]ssh.Host, targets map[string][]string, jumps map[string]string) ([]ssh.Host, error) {
 	var dynamic []ssh.Host
 
 	for _, p := range providers {
@@ -19,6 +21,11 @@ func resolveProviders(ctx context.Context, providers []Provider, hosts map[strin
 			return nil, err
 		}
 
+		// Providers return instances in no particular order
+		slices.SortFunc(instances, func(a, b cloud.CloudInstance) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+
 		for _, inst := range instances {
 			if inst.Name == "" {
 				continue
@@ -44,6 +51,9 @@ func resolveProviders(ctx context.Context, providers []Provider, hosts map[strin
 			}
 			hosts[inst.Name] = host
 			dynamic = append(dynamic, host)
+			if p.SSH.Jump != "" {
+				jumps[inst.Name] = p.SSH.Jump
+			}
 
 			for _, t := range p.Targets {
 				targets[t] = append(targets[t], inst.Name)
@@ -70,18 +80,46 @@ func fetchInstances(ctx context.Context, p Provider) ([]cloud.CloudInstance, err
 
 func instanceToHost(inst cloud.CloudInstance, p Provider) (ssh.Host, error) {
 	addr := ""
-	if inst.PublicIPv4 != nil {
+	if p.SSH.UsePrivateIP {
+		if inst.PrivateIP == nil {
+			return ssh.Host{}, fmt.Errorf("use_private_ip is set but instance has no private IP")
+		}
+		addr = inst.PrivateIP.String()
+	} else if inst.PublicIPv4 != nil {
 		addr = inst.PublicIPv4.String()
 	} else if inst.PublicIPv6 != nil {
 		addr = inst.PublicIPv6.String()
 	}
 
+	if !ssh.ValidHostKeyPolicy(p.SSH.HostKeyChecking) {
+		return ssh.Host{}, fmt.Errorf("invalid host_key_checking %q (expected strict, tofu or off)", p.SSH.HostKeyChecking)
+	}
+
 	host := ssh.Host{
-		Name:    inst.Name,
-		Address: addr,
-		User:    p.SSH.User,
-		Port:    p.SSH.Port,
+		Name:          inst.Name,
+		Address:       addr,
+		User:          p.SSH.User,
+		Port:          p.SSH.Port,
+		HostKeyPolicy: p.SSH.HostKeyChecking,
+		Fingerprints:  p.SSH.Fingerprints,
+		AuthMethods:   p.SSH.Auth,
+	}
+
+	if err := validateAuthMethods(p.SSH.Auth); err != nil {
+		return ssh.Host{}, err
+	}
+
+	connectTimeout, err := parseDurationSetting("connect_timeout", p.SSH.ConnectTimeout, ssh.DefaultConnectTimeout)
+	if err != nil {
+		return ssh.Host{}, err
+	}
+	host.ConnectTimeout = connectTimeout
+
+	keepAlive, err := parseDurationSetting("keepalive_interval", p.SSH.KeepAlive, ssh.DefaultKeepAliveInterval)
+	if err != nil {
+		return ssh.Host{}, err
 	}
+	host.KeepAlive = keepAlive
 
 	if p.SSH.IdentityFile != "" {
 		keyPath, err := utils.ExpandPath(p.SSH.IdentityFile)
@@ -91,5 +129,25 @@ func instanceToHost(inst cloud.CloudInstance, p Provider) (ssh.Host, error) {
 		host.KeyPath = keyPath
 	}
 
+	if p.SSH.CertificateFile != "" {
+		certPath, err := utils.ExpandPath(p.SSH.CertificateFile)
+		if err != nil {
+			return ssh.Host{}, fmt.Errorf("failed to expand certificate_file: %w", err)
+		}
+		host.CertPath = certPath
+	}
+
+	if pinned, ok := p.SSH.HostFingerprints[inst.Name]; ok {
+		host.Fingerprints = pinned
+	}
+
+	if p.SSH.KnownHosts != "" {
+		knownHosts, err := utils.ExpandPath(p.SSH.KnownHosts)
+		if err != nil {
+			return ssh.Host{}, fmt.Errorf("failed to expand known_hosts: %w", err)
+		}
+		host.KnownHosts = knownHosts
+	}
+
 	return host, nil
 }
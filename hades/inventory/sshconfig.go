@@ -0,0 +1,277 @@
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+	"github.com/SoftKiwiGames/hades/hades/utils"
+	"github.com/kevinburke/ssh_config"
+)
+
+// maxProxyJumpDepth bounds ProxyJump recursion in ssh_config (guards against loops)
+const maxProxyJumpDepth = 10
+
+var (
+	sshConfigMu    sync.Mutex
+	sshConfigCache = make(map[string]*ssh_config.Config)
+)
+
+// loadSSHConfig parses an ssh_config file (cached per path).
+// A missing default ~/.ssh/config is not an error; a missing explicit file is.
+func loadSSHConfig(path string) (*ssh_config.Config, error) {
+	explicit := path != ""
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".ssh", "config")
+	}
+
+	path, err := utils.ExpandPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand ssh_config_file: %w", err)
+	}
+
+	sshConfigMu.Lock()
+	defer sshConfigMu.Unlock()
+
+	if cfg, ok := sshConfigCache[path]; ok {
+		return cfg, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			sshConfigCache[path] = nil
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open ssh_config %s: %w", path, err)
+	}
+	defer f.Close()
+
+	cfg, err := ssh_config.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ssh_config %s: %w", path, err)
+	}
+
+	sshConfigCache[path] = cfg
+	return cfg, nil
+}
+
+// needsSSHConfig reports whether ssh_config should be consulted for the host:
+// an alias or ssh_config file is set explicitly, or the host has no addr.
+// Hosts with an addr don't pick up ~/.ssh/config unless asked to.
+func (h hostDef) needsSSHConfig(defaultConfigFile string) bool {
+	return h.SSHConfig != "" || h.SSHConfigFile != "" || defaultConfigFile != "" || h.Addr == ""
+}
+
+// applySSHConfig fills missing connection fields from ssh_config.
+// Fields set in the inventory always win over ssh_config.
+func (h hostDef) applySSHConfig(name string, defaultConfigFile string) (hostDef, error) {
+	if !h.needsSSHConfig(defaultConfigFile) {
+		return h, nil
+	}
+
+	configFile := h.SSHConfigFile
+	if configFile == "" {
+		configFile = defaultConfigFile
+	}
+
+	cfg, err := loadSSHConfig(configFile)
+	if err != nil {
+		return h, err
+	}
+	if cfg == nil {
+		return h, nil
+	}
+
+	// Match the alias like `ssh <alias>` would: explicit alias, then addr, then host name
+	alias := h.SSHConfig
+	if alias == "" {
+		alias = h.Addr
+	}
+	if alias == "" {
+		alias = name
+	}
+
+	entry, err := lookupSSHConfig(cfg, alias)
+	if err != nil {
+		return h, fmt.Errorf("ssh_config %q: %w", alias, err)
+	}
+
+	if h.Addr == "" {
+		h.Addr = entry.Address
+	}
+	if h.User == "" {
+		h.User = entry.User
+	}
+	if h.IdentityFile == "" {
+		h.IdentityFile = entry.KeyPath
+	}
+	if h.Port == 0 {
+		h.Port = entry.Port
+	}
+
+	// Inventory jumps reference inventory hosts; ProxyJump references ssh_config aliases
+	if h.Jump == "" {
+		jump, err := proxyJumpHost(cfg, alias, entry.proxyJump, 0)
+		if err != nil {
+			return h, fmt.Errorf("ssh_config %q: %w", alias, err)
+		}
+		h.jumpHost = jump
+	}
+
+	return h, nil
+}
+
+// sshConfigEntry is the connection info ssh_config yields for an alias
+type sshConfigEntry struct {
+	ssh.Host
+	proxyJump string
+}
+
+// lookupSSHConfig reads HostName, User, Port, IdentityFile and ProxyJump for alias
+func lookupSSHConfig(cfg *ssh_config.Config, alias string) (sshConfigEntry, error) {
+	entry := sshConfigEntry{Host: ssh.Host{Name: alias, Address: alias}}
+
+	get := func(key string) (string, error) {
+		value, err := cfg.Get(alias, key)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		return value, nil
+	}
+
+	hostName, err := get("HostName")
+	if err != nil {
+		return entry, err
+	}
+	if hostName != "" {
+		entry.Address = expandSSHTokens(hostName, alias, "")
+	}
+
+	if entry.User, err = get("User"); err != nil {
+		return entry, err
+	}
+
+	port, err := get("Port")
+	if err != nil {
+		return entry, err
+	}
+	if port != "" {
+		if entry.Port, err = strconv.Atoi(port); err != nil {
+			return entry, fmt.Errorf("invalid Port %q", port)
+		}
+	}
+
+	identityFile, err := get("IdentityFile")
+	if err != nil {
+		return entry, err
+	}
+	if identityFile != "" {
+		entry.KeyPath, err = utils.ExpandPath(expandSSHTokens(identityFile, entry.Address, entry.User))
+		if err != nil {
+			return entry, fmt.Errorf("failed to expand IdentityFile: %w", err)
+		}
+	}
+
+	if entry.proxyJump, err = get("ProxyJump"); err != nil {
+		return entry, err
+	}
+
+	return entry, nil
+}
+
+// proxyJumpHost resolves a ProxyJump value ([user@]host[:port], comma separated
+// for chains) into the bastion the target is dialed through
+func proxyJumpHost(cfg *ssh_config.Config, alias string, proxyJump string, depth int) (*ssh.Host, error) {
+	if proxyJump == "" || strings.EqualFold(proxyJump, "none") {
+		return nil, nil
+	}
+	if depth >= maxProxyJumpDepth {
+		return nil, fmt.Errorf("ProxyJump nested too deeply (loop at %q?)", alias)
+	}
+
+	var jump *ssh.Host
+	for i, hop := range strings.Split(proxyJump, ",") {
+		hopUser, hopAlias, hopPort, err := parseProxyJumpHop(strings.TrimSpace(hop))
+		if err != nil {
+			return nil, err
+		}
+
+		entry, err := lookupSSHConfig(cfg, hopAlias)
+		if err != nil {
+			return nil, fmt.Errorf("ProxyJump %q: %w", hopAlias, err)
+		}
+		host := entry.Host
+		if hopUser != "" {
+			host.User = hopUser
+		}
+		if hopPort != 0 {
+			host.Port = hopPort
+		}
+
+		if i == 0 {
+			// The first hop may itself need a ProxyJump from ssh_config
+			host.Jump, err = proxyJumpHost(cfg, hopAlias, entry.proxyJump, depth+1)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			host.Jump = jump
+		}
+		jump = &host
+	}
+
+	return jump, nil
+}
+
+// parseProxyJumpHop splits a [user@]host[:port] ProxyJump hop
+func parseProxyJumpHop(hop string) (string, string, int, error) {
+	var hopUser string
+	if at := strings.LastIndex(hop, "@"); at >= 0 {
+		hopUser, hop = hop[:at], hop[at+1:]
+	}
+
+	host, port := hop, 0
+	if colon := strings.LastIndex(hop, ":"); colon >= 0 && !strings.Contains(hop[:colon], ":") {
+		p, err := strconv.Atoi(hop[colon+1:])
+		if err != nil {
+			return "", "", 0, fmt.Errorf("invalid ProxyJump port in %q", hop)
+		}
+		host, port = hop[:colon], p
+	}
+
+	if host == "" {
+		return "", "", 0, fmt.Errorf("invalid ProxyJump hop %q", hop)
+	}
+	return hopUser, host, port, nil
+}
+
+// expandSSHTokens expands the ssh_config tokens Hades understands (%d, %h, %r, %u, %%)
+func expandSSHTokens(s string, host string, remoteUser string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	home, _ := os.UserHomeDir()
+	localUser := ""
+	if u, err := user.Current(); err == nil {
+		localUser = u.Username
+	}
+
+	return strings.NewReplacer(
+		"%%", "%",
+		"%d", home,
+		"%h", host,
+		"%r", remoteUser,
+		"%u", localUser,
+	).Replace(s)
+}
@@ -0,0 +1,120 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSSHConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestToHost_SSHConfigAlias(t *testing.T) {
+	configFile := writeSSHConfig(t, `
+Host bastion
+  HostName bastion.example.com
+  User ops
+
+Host prod-web
+  HostName 10.0.1.5
+  User deploy
+  Port 2222
+  IdentityFile /keys/%r.pem
+  ProxyJump bastion
+`)
+
+	def := hostDef{SSHConfig: "prod-web", SSHConfigFile: configFile}
+	host, err := def.toHost("web-1", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if host.Address != "10.0.1.5" || host.User != "deploy" || host.Port != 2222 {
+		t.Errorf("Unexpected connection details: %s@%s:%d", host.User, host.Address, host.Port)
+	}
+	if host.KeyPath != "/keys/deploy.pem" {
+		t.Errorf("Expected IdentityFile /keys/deploy.pem, got %q", host.KeyPath)
+	}
+	if host.Jump == nil || host.Jump.Address != "bastion.example.com" || host.Jump.User != "ops" {
+		t.Errorf("Expected jump via ops@bastion.example.com, got %+v", host.Jump)
+	}
+}
+
+func TestToHost_InventoryFieldsWin(t *testing.T) {
+	configFile := writeSSHConfig(t, `
+Host 192.0.2.10
+  User root
+  Port 2222
+`)
+
+	def := hostDef{Addr: "192.0.2.10", User: "deploy"}
+	host, err := def.toHost("web-1", configFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if host.User != "deploy" {
+		t.Errorf("Expected inventory user to win, got %q", host.User)
+	}
+	if host.Port != 2222 {
+		t.Errorf("Expected missing port to come from ssh_config, got %d", host.Port)
+	}
+}
+
+func TestToHost_AddrSkipsDefaultSSHConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "config"), []byte("Host 192.0.2.10\n  User root\n  Port 2222\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	def := hostDef{Addr: "192.0.2.10"}
+	host, err := def.toHost("web-1", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if host.User != "" || host.Port != 0 {
+		t.Errorf("Expected ~/.ssh/config to be ignored, got %s:%d", host.User, host.Port)
+	}
+}
+
+func TestToHost_MissingExplicitSSHConfig(t *testing.T) {
+	def := hostDef{SSHConfig: "web", SSHConfigFile: filepath.Join(t.TempDir(), "missing")}
+	if _, err := def.toHost("web-1", ""); err == nil {
+		t.Error("Expected error for missing ssh_config_file")
+	}
+}
+
+func TestParseProxyJumpHop(t *testing.T) {
+	tests := []struct {
+		hop      string
+		wantUser string
+		wantHost string
+		wantPort int
+	}{
+		{hop: "bastion", wantHost: "bastion"},
+		{hop: "ops@bastion", wantUser: "ops", wantHost: "bastion"},
+		{hop: "ops@bastion:2222", wantUser: "ops", wantHost: "bastion", wantPort: 2222},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hop, func(t *testing.T) {
+			user, host, port, err := parseProxyJumpHop(tt.hop)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if user != tt.wantUser || host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("Expected %s@%s:%d, got %s@%s:%d", tt.wantUser, tt.wantHost, tt.wantPort, user, host, port)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package loader
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+// BatchCheckStep returns the step that runs a step's batch_check job, with the
+// step's env
+func BatchCheckStep(step schema.Step) schema.Step {
+	return schema.Step{
+		Name: step.Name + " (batch check)",
+		Job:  step.BatchCheck.Job,
+		Env:  step.Env,
+	}
+}
+
+// BatchCheckJob returns the job a batch check runs and its name: the named job,
+// or one running the check command
+func BatchCheckJob(file *schema.File, check *schema.BatchCheck) (*schema.Job, string) {
+	if check.Job != "" {
+		job := file.Jobs[check.Job]
+		return &job, check.Job
+	}
+
+	return &schema.Job{
+		Local: check.Local,
+		Actions: []schema.Action{{
+			Run:     &schema.ActionRun{Cmd: check.Run},
+			Retries: check.Retries,
+			Delay:   check.Delay,
+		}},
+	}, "batch_check"
+}
+
+// validateBatchGates checks a step's pause_between_batches and batch_check
+func validateBatchGates(step schema.Step) error {
+	if step.PauseBetweenBatches != "" {
+		pause, err := time.ParseDuration(step.PauseBetweenBatches)
+		if err != nil {
+			return fmt.Errorf("invalid pause_between_batches %q: %w", step.PauseBetweenBatches, err)
+		}
+		if pause < 0 {
+			return fmt.Errorf("invalid pause_between_batches %q: must not be negative", step.PauseBetweenBatches)
+		}
+	}
+
+	check := step.BatchCheck
+	if check == nil {
+		return nil
+	}
+	if (check.Job == "") == (check.Run == "") {
+		return fmt.Errorf("batch_check needs exactly one of job or run")
+	}
+	if check.Job != "" && (check.Local || check.Retries != 0 || check.Delay != "") {
+		return fmt.Errorf("batch_check: local, retries and delay only apply to run (set them on the job)")
+	}
+	if _, err := ActionRetry(&schema.Action{Retries: check.Retries, Delay: check.Delay}); err != nil {
+		return fmt.Errorf("batch_check: %w", err)
+	}
+	return nil
+}
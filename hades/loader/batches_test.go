@@ -0,0 +1,38 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+func TestValidateBatchGates(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    schema.Step
+		wantErr bool
+	}{
+		{name: "none", step: schema.Step{}},
+		{name: "pause", step: schema.Step{PauseBetweenBatches: "30s"}},
+		{name: "invalid pause", step: schema.Step{PauseBetweenBatches: "soon"}, wantErr: true},
+		{name: "negative pause", step: schema.Step{PauseBetweenBatches: "-1s"}, wantErr: true},
+		{name: "job check", step: schema.Step{BatchCheck: &schema.BatchCheck{Job: "health"}}},
+		{name: "local run check", step: schema.Step{BatchCheck: &schema.BatchCheck{Run: "curl -fsS http://${HADES_HOST_ADDR}/health", Local: true, Retries: 5, Delay: "2s"}}},
+		{name: "empty check", step: schema.Step{BatchCheck: &schema.BatchCheck{}}, wantErr: true},
+		{name: "job and run", step: schema.Step{BatchCheck: &schema.BatchCheck{Job: "health", Run: "true"}}, wantErr: true},
+		{name: "retries on job", step: schema.Step{BatchCheck: &schema.BatchCheck{Job: "health", Retries: 3}}, wantErr: true},
+		{name: "invalid delay", step: schema.Step{BatchCheck: &schema.BatchCheck{Run: "true", Delay: "later"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBatchGates(tt.step)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
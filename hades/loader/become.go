@@ -0,0 +1,36 @@
+package loader
+
+import "github.com/SoftKiwiGames/hades/hades/schema"
+
+// defaultBecomeUser is the user become switches to when become_user isn't set
+const defaultBecomeUser = "root"
+
+// JobBecome returns the user a job's guard and actions run as through sudo
+// ("" = the inventory user). Setting become_user implies become.
+func JobBecome(job *schema.Job) string {
+	if !job.Become && job.BecomeUser == "" {
+		return ""
+	}
+	if job.BecomeUser != "" {
+		return job.BecomeUser
+	}
+	return defaultBecomeUser
+}
+
+// ActionBecome returns the user an action runs as through sudo, applying the
+// action's become/become_user over the job's
+func ActionBecome(job *schema.Job, action *schema.Action) string {
+	if action.Become != nil && !*action.Become {
+		return ""
+	}
+	if action.Become == nil && action.BecomeUser == "" {
+		return JobBecome(job)
+	}
+	if action.BecomeUser != "" {
+		return action.BecomeUser
+	}
+	if job.BecomeUser != "" {
+		return job.BecomeUser
+	}
+	return defaultBecomeUser
+}
@@ -0,0 +1,35 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+func TestActionBecome(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name   string
+		job    schema.Job
+		action schema.Action
+		want   string
+	}{
+		{name: "no become", want: ""},
+		{name: "job become", job: schema.Job{Become: true}, want: "root"},
+		{name: "job become_user", job: schema.Job{Become: true, BecomeUser: "postgres"}, want: "postgres"},
+		{name: "job become_user implies become", job: schema.Job{BecomeUser: "postgres"}, want: "postgres"},
+		{name: "action become", action: schema.Action{Become: &yes}, want: "root"},
+		{name: "action inherits job user", job: schema.Job{BecomeUser: "postgres"}, action: schema.Action{Become: &yes}, want: "postgres"},
+		{name: "action become_user", job: schema.Job{Become: true}, action: schema.Action{BecomeUser: "app"}, want: "app"},
+		{name: "action opts out", job: schema.Job{Become: true}, action: schema.Action{Become: &no}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ActionBecome(&tt.job, &tt.action); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package loader
+
+import "github.com/SoftKiwiGames/hades/hades/schema"
+
+// ExportEnv reports whether a job's env is exported into its commands'
+// environment (on unless export_env is false)
+func ExportEnv(job *schema.Job) bool {
+	return job.ExportEnv == nil || *job.ExportEnv
+}
@@ -0,0 +1,118 @@
+package loader
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+// validateJobAction checks an action that includes another job: the job must
+// exist, run in the same place and define every env var the action overrides.
+// Required env vars are checked per plan by validateIncludedEnv, since they may
+// also come from the plan, the step or the CLI.
+func validateJobAction(file *schema.File, job *schema.Job, action *schema.Action) error {
+	if action.Job.Name == "" {
+		return fmt.Errorf("job: name is required")
+	}
+	included, ok := file.Jobs[action.Job.Name]
+	if !ok {
+		return fmt.Errorf("job: %q not found", action.Job.Name)
+	}
+	if included.Local != job.Local {
+		return fmt.Errorf("job: %q must have the same local setting as the including job", action.Job.Name)
+	}
+
+	// The included job's own settings apply to its actions
+	var option string
+	switch {
+	case action.Timeout != "":
+		option = "timeout"
+	case action.Become != nil || action.BecomeUser != "":
+		option = "become"
+	case action.Retries > 0 || action.Delay != "" || action.Backoff != 0 || action.Until != "":
+		option = "retries"
+	}
+	if option != "" {
+		return fmt.Errorf("job: %s is not supported on job actions", option)
+	}
+
+	for name := range action.Job.Env {
+		if strings.HasPrefix(name, "HADES_") {
+			return fmt.Errorf("job: cannot define HADES_* environment variables: %s", name)
+		}
+		if _, ok := included.Env[name]; !ok {
+			return fmt.Errorf("job: unknown environment variable %q (not defined in job %q)", name, action.Job.Name)
+		}
+	}
+	return nil
+}
+
+// validateIncludedEnv checks that every job included by job, directly or
+// through other jobs, gets the env vars it requires. provided is the env job
+// runs with (plan, step and CLI); an included job sees it with job's defaults
+// and the action's overrides on top.
+func validateIncludedEnv(file *schema.File, job *schema.Job, provided map[string]string) error {
+	env := MergeEnv(job, provided)
+	for _, action := range job.Actions {
+		if action.Job == nil {
+			continue
+		}
+		included, ok := file.Jobs[action.Job.Name]
+		if !ok {
+			continue
+		}
+
+		includedEnv := maps.Clone(env)
+		maps.Copy(includedEnv, action.Job.Env)
+		if err := ValidateEnvContract(&included, includedEnv); err != nil {
+			return fmt.Errorf("job %q: %w", action.Job.Name, err)
+		}
+		if err := validateIncludedEnv(file, &included, includedEnv); err != nil {
+			return fmt.Errorf("job %q: %w", action.Job.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateJobCycles checks that no job includes itself, directly or through
+// other jobs
+func validateJobCycles(file *schema.File) error {
+	var names []string
+	for name := range file.Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	done := make(map[string]bool)
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		if i := slices.Index(path, name); i >= 0 {
+			return fmt.Errorf("jobs include each other: %s", strings.Join(append(path[i:], name), " -> "))
+		}
+		if done[name] {
+			return nil
+		}
+		path = append(path, name)
+		for _, action := range file.Jobs[name].Actions {
+			if action.Job == nil {
+				continue
+			}
+			if err := visit(action.Job.Name, path); err != nil {
+				return err
+			}
+		}
+		done[name] = true
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
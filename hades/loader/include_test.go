@@ -0,0 +1,155 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestActionJob_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		wantName string
+		wantEnv  map[string]string
+	}{
+		{name: "bare name", yaml: "job: install-caddy", wantName: "install-caddy"},
+		{name: "with env", yaml: "job:\n  name: install-caddy\n  env:\n    VERSION: \"2.7\"\n", wantName: "install-caddy", wantEnv: map[string]string{"VERSION": "2.7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var action schema.Action
+			if err := yaml.Unmarshal([]byte(tt.yaml), &action); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if action.Job == nil || action.Job.Name != tt.wantName {
+				t.Fatalf("Expected job %q, got %+v", tt.wantName, action.Job)
+			}
+			if action.Job.Env["VERSION"] != tt.wantEnv["VERSION"] {
+				t.Errorf("Expected %q, got %q", tt.wantEnv["VERSION"], action.Job.Env["VERSION"])
+			}
+		})
+	}
+}
+
+func TestValidateJobAction(t *testing.T) {
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"caddy": {Env: map[string]schema.Env{"VERSION": {}, "PORT": {Default: "80"}}},
+			"build": {Local: true},
+		},
+	}
+	parent := &schema.Job{Env: map[string]schema.Env{"RELEASE": {}}}
+	withVersion := &schema.Job{Env: map[string]schema.Env{"VERSION": {Default: "2.7"}}}
+	become := true
+
+	tests := []struct {
+		name    string
+		job     *schema.Job
+		action  schema.Action
+		wantErr bool
+	}{
+		{name: "override", job: parent, action: schema.Action{Job: &schema.ActionJob{Name: "caddy", Env: map[string]string{"VERSION": "${RELEASE}"}}}},
+		{name: "from including job", job: withVersion, action: schema.Action{Job: &schema.ActionJob{Name: "caddy"}}},
+		{name: "when", job: withVersion, action: schema.Action{Job: &schema.ActionJob{Name: "caddy"}, When: &schema.When{Expr: `RELEASE != ""`}}},
+		{name: "required env left to the plan", job: parent, action: schema.Action{Job: &schema.ActionJob{Name: "caddy"}}},
+		{name: "unknown env", job: withVersion, action: schema.Action{Job: &schema.ActionJob{Name: "caddy", Env: map[string]string{"HOST": "a"}}}, wantErr: true},
+		{name: "hades env", job: withVersion, action: schema.Action{Job: &schema.ActionJob{Name: "caddy", Env: map[string]string{"HADES_HOST_NAME": "a"}}}, wantErr: true},
+		{name: "unknown job", job: parent, action: schema.Action{Job: &schema.ActionJob{Name: "nginx"}}, wantErr: true},
+		{name: "no name", job: parent, action: schema.Action{Job: &schema.ActionJob{}}, wantErr: true},
+		{name: "local mismatch", job: parent, action: schema.Action{Job: &schema.ActionJob{Name: "build"}}, wantErr: true},
+		{name: "retries", job: withVersion, action: schema.Action{Job: &schema.ActionJob{Name: "caddy"}, Retries: 2}, wantErr: true},
+		{name: "become", job: withVersion, action: schema.Action{Job: &schema.ActionJob{Name: "caddy"}, Become: &become}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateJobAction(file, tt.job, &tt.action)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateIncludedEnv(t *testing.T) {
+	include := func(name string, env map[string]string) schema.Action {
+		return schema.Action{Job: &schema.ActionJob{Name: name, Env: env}}
+	}
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"caddy":  {Env: map[string]schema.Env{"VERSION": {}, "PORT": {Default: "80"}}},
+			"web":    {Actions: []schema.Action{include("caddy", nil)}},
+			"pinned": {Env: map[string]schema.Env{"VERSION": {Default: "2.7"}}, Actions: []schema.Action{include("caddy", nil)}},
+			"nested": {Actions: []schema.Action{include("web", nil)}},
+			"site":   {Actions: []schema.Action{include("caddy", map[string]string{"VERSION": "2.8"})}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		job      string
+		provided map[string]string
+		wantErr  bool
+	}{
+		{name: "from plan, step or CLI", job: "web", provided: map[string]string{"VERSION": "2.7"}},
+		{name: "from including job defaults", job: "pinned"},
+		{name: "from overrides", job: "site"},
+		{name: "missing", job: "web", wantErr: true},
+		{name: "missing in nested include", job: "nested", wantErr: true},
+		{name: "nested", job: "nested", provided: map[string]string{"VERSION": "2.7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := file.Jobs[tt.job]
+			err := validateIncludedEnv(file, &job, tt.provided)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateJobCycles(t *testing.T) {
+	include := func(names ...string) schema.Job {
+		var job schema.Job
+		for _, name := range names {
+			job.Actions = append(job.Actions, schema.Action{Job: &schema.ActionJob{Name: name}})
+		}
+		return job
+	}
+
+	tests := []struct {
+		name    string
+		jobs    map[string]schema.Job
+		wantErr string
+	}{
+		{name: "shared include", jobs: map[string]schema.Job{"a": include("c"), "b": include("c", "c"), "c": include()}},
+		{name: "self", jobs: map[string]schema.Job{"a": include("a")}, wantErr: "jobs include each other: a -> a"},
+		{name: "indirect", jobs: map[string]schema.Job{"a": include("b"), "b": include("c"), "c": include("a")}, wantErr: "jobs include each other: a -> b -> c -> a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateJobCycles(&schema.File{Jobs: tt.jobs})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
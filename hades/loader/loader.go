@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/SoftKiwiGames/hades/hades/rollout"
 	"github.com/SoftKiwiGames/hades/hades/schema"
 	"github.com/SoftKiwiGames/hades/hades/utils"
 	"gopkg.in/yaml.v3"
@@ -122,16 +123,78 @@ func (l *Loader) LoadPlan(file *schema.File, name string) (*schema.Plan, error)
 func (l *Loader) Validate(file *schema.File) error {
 	// Check that all steps reference existing jobs
 	for planName, plan := range file.Plans {
-		for i, step := range plan.Steps {
+		if err := validateNeeds(plan); err != nil {
+			return fmt.Errorf("plan %q: %w", planName, err)
+		}
+		if err := validateStrategy(plan); err != nil {
+			return fmt.Errorf("plan %q: %w", planName, err)
+		}
+		for i, step := range PlanSteps(plan) {
 			if _, ok := file.Jobs[step.Job]; !ok {
 				return fmt.Errorf("plan %q step %d references non-existent job %q", planName, i, step.Job)
 			}
+			if _, err := rollout.ParseMaxFail(step.MaxFail, 0); err != nil {
+				return fmt.Errorf("plan %q step %d: %w", planName, i, err)
+			}
+			if _, err := rollout.ParseMaxFail(step.MaxFailPerBatch, 0); err != nil {
+				return fmt.Errorf("plan %q step %d: invalid max_fail_per_batch: %w", planName, i, err)
+			}
+			if _, err := rollout.ParseStrategy(string(step.Parallelism), 0); err != nil {
+				return fmt.Errorf("plan %q step %d: invalid parallelism: %w", planName, i, err)
+			}
+			if _, err := rollout.ParseLimit(step.Limit, 0); err != nil {
+				return fmt.Errorf("plan %q step %d: %w", planName, i, err)
+			}
+			if _, err := rollout.OrderHosts(nil, step.Order); err != nil {
+				return fmt.Errorf("plan %q step %d: %w", planName, i, err)
+			}
+			if err := validateBatchGates(step); err != nil {
+				return fmt.Errorf("plan %q step %d: %w", planName, i, err)
+			}
+			if err := validateStepRegisters(file, plan, step); err != nil {
+				return fmt.Errorf("plan %q step %d: %w", planName, i, err)
+			}
 		}
 	}
 
 	// Check that no action has more than one field set
 	for jobName, job := range file.Jobs {
+		if job.Timeout != "" {
+			if _, err := parseTimeout(job.Timeout); err != nil {
+				return fmt.Errorf("job %q: %w", jobName, err)
+			}
+		}
+
+		if job.Local && JobBecome(&job) != "" {
+			return fmt.Errorf("job %q: become is not supported for local jobs", jobName)
+		}
+
 		for i, action := range job.Actions {
+			if job.Local && ActionBecome(&job, &action) != "" {
+				return fmt.Errorf("job %q action %d: become is not supported for local jobs", jobName, i)
+			}
+			if action.Timeout != "" {
+				if _, err := parseTimeout(action.Timeout); err != nil {
+					return fmt.Errorf("job %q action %d: %w", jobName, i, err)
+				}
+			}
+			if action.Run != nil {
+				if err := validateRun(&job, action.Run); err != nil {
+					return fmt.Errorf("job %q action %d: %w", jobName, i, err)
+				}
+			}
+			if err := validateRegister(&job, &action); err != nil {
+				return fmt.Errorf("job %q action %d: %w", jobName, i, err)
+			}
+			if action.When != nil {
+				if err := validateWhen(action.When); err != nil {
+					return fmt.Errorf("job %q action %d: %w", jobName, i, err)
+				}
+			}
+			if _, err := ActionRetry(&action); err != nil {
+				return fmt.Errorf("job %q action %d: %w", jobName, i, err)
+			}
+
 			count := 0
 			if action.Run != nil {
 				count++
@@ -157,6 +220,12 @@ func (l *Loader) Validate(file *schema.File) error {
 			if action.Gpg != nil {
 				count++
 			}
+			if action.Job != nil {
+				count++
+				if err := validateJobAction(file, &job, &action); err != nil {
+					return fmt.Errorf("job %q action %d: %w", jobName, i, err)
+				}
+			}
 			if count == 0 {
 				return fmt.Errorf("job %q action %d has no action type set", jobName, i)
 			}
@@ -166,5 +235,5 @@ func (l *Loader) Validate(file *schema.File) error {
 		}
 	}
 
-	return nil
+	return validateJobCycles(file)
 }
@@ -0,0 +1,126 @@
+package loader
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+// UsesNeeds reports whether any step of the plan declares needs, including
+// an empty list
+func UsesNeeds(plan *schema.Plan) bool {
+	for _, step := range plan.Steps {
+		if step.Needs != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// StepNeeds returns the indices of the steps each plan step waits for. A step
+// with needs waits for those steps only (none for `needs: []`); a step
+// without needs waits for the one before it, as in a plan without needs.
+func StepNeeds(plan *schema.Plan) [][]int {
+	index := make(map[string]int)
+	for i, step := range plan.Steps {
+		index[step.Name] = i
+	}
+
+	needs := make([][]int, len(plan.Steps))
+	for i, step := range plan.Steps {
+		if step.Needs == nil {
+			if i > 0 {
+				needs[i] = []int{i - 1}
+			}
+			continue
+		}
+		for _, name := range step.Needs {
+			needs[i] = append(needs[i], index[name])
+		}
+	}
+	return needs
+}
+
+// StepLevels groups the plan's steps into levels: each step's needs are all
+// in earlier levels, so the steps of a level can run together
+func StepLevels(plan *schema.Plan) ([][]string, error) {
+	needs := StepNeeds(plan)
+	level := make([]int, len(plan.Steps))
+	placed := make([]bool, len(plan.Steps))
+
+	var levels [][]string
+	for remaining := len(plan.Steps); remaining > 0; {
+		var current []int
+		for i := range plan.Steps {
+			if placed[i] {
+				continue
+			}
+			ready := true
+			for _, need := range needs[i] {
+				if !placed[need] || level[need] == len(levels) {
+					ready = false
+				}
+			}
+			if ready {
+				current = append(current, i)
+			}
+		}
+
+		if len(current) == 0 {
+			var cycle []string
+			implicit := false
+			for i, step := range plan.Steps {
+				if !placed[i] {
+					cycle = append(cycle, step.Name)
+					implicit = implicit || (step.Needs == nil && i > 0)
+				}
+			}
+			if implicit {
+				return nil, fmt.Errorf("needs form a cycle between steps: %s (steps without needs wait for the step before them; use needs: [] to start one right away)", strings.Join(cycle, ", "))
+			}
+			return nil, fmt.Errorf("needs form a cycle between steps: %s", strings.Join(cycle, ", "))
+		}
+
+		var names []string
+		for _, i := range current {
+			placed[i] = true
+			level[i] = len(levels)
+			names = append(names, plan.Steps[i].Name)
+		}
+		levels = append(levels, names)
+		remaining -= len(current)
+	}
+	return levels, nil
+}
+
+// validateNeeds checks that needs name other steps of the plan and don't
+// form a cycle
+func validateNeeds(plan schema.Plan) error {
+	if !UsesNeeds(&plan) {
+		return nil
+	}
+
+	var names []string
+	for _, step := range plan.Steps {
+		if slices.Contains(names, step.Name) {
+			return fmt.Errorf("step name %q is used twice (needs refer to steps by name)", step.Name)
+		}
+		names = append(names, step.Name)
+	}
+
+	for _, step := range plan.Steps {
+		for _, need := range step.Needs {
+			if need == step.Name {
+				return fmt.Errorf("step %q needs itself", step.Name)
+			}
+			if !slices.Contains(names, need) {
+				return fmt.Errorf("step %q needs unknown step %q", step.Name, need)
+			}
+		}
+	}
+
+	_, err := StepLevels(&plan)
+	return err
+}
@@ -0,0 +1,108 @@
+package loader
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+func TestStepLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []schema.Step
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:  "sequential without needs",
+			steps: []schema.Step{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			want:  [][]string{{"a"}, {"b"}, {"c"}},
+		},
+		{
+			name: "diamond",
+			steps: []schema.Step{
+				{Name: "build"},
+				{Name: "db", Needs: []string{"build"}},
+				{Name: "app", Needs: []string{"build"}},
+				{Name: "smoke", Needs: []string{"db", "app"}},
+			},
+			want: [][]string{{"build"}, {"db", "app"}, {"smoke"}},
+		},
+		{
+			name: "independent",
+			steps: []schema.Step{
+				{Name: "report", Needs: []string{"web"}},
+				{Name: "web", Needs: []string{}},
+				{Name: "worker", Needs: []string{}},
+			},
+			want: [][]string{{"web", "worker"}, {"report"}},
+		},
+		{
+			name: "steps without needs keep plan order",
+			steps: []schema.Step{
+				{Name: "build"},
+				{Name: "lint", Needs: []string{}},
+				{Name: "deploy"},
+				{Name: "smoke"},
+			},
+			want: [][]string{{"build", "lint"}, {"deploy"}, {"smoke"}},
+		},
+		{
+			name: "cycle",
+			steps: []schema.Step{
+				{Name: "a", Needs: []string{"c"}},
+				{Name: "b", Needs: []string{"a"}},
+				{Name: "c", Needs: []string{"b"}},
+				{Name: "d"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := StepLevels(&schema.Plan{Steps: tt.steps})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidateNeeds(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []schema.Step
+		wantErr bool
+	}{
+		{name: "no needs", steps: []schema.Step{{Name: "a"}, {Name: "a"}}},
+		{name: "valid", steps: []schema.Step{{Name: "a"}, {Name: "b", Needs: []string{"a"}}}},
+		{name: "implicit cycle", steps: []schema.Step{{Name: "a", Needs: []string{"b"}}, {Name: "b"}}, wantErr: true},
+		{name: "unknown step", steps: []schema.Step{{Name: "a", Needs: []string{"missing"}}}, wantErr: true},
+		{name: "self", steps: []schema.Step{{Name: "a", Needs: []string{"a"}}}, wantErr: true},
+		{name: "duplicate name", steps: []schema.Step{{Name: "a"}, {Name: "a"}, {Name: "b", Needs: []string{"a"}}}, wantErr: true},
+		{name: "cycle", steps: []schema.Step{{Name: "a", Needs: []string{"b"}}, {Name: "b", Needs: []string{"a"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateNeeds(schema.Plan{Steps: tt.steps})
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
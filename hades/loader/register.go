@@ -0,0 +1,106 @@
+package loader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+// Register scopes: job variables last until the job ends on the host,
+// plan variables are also available to later steps on the same host
+const (
+	RegisterScopeJob  = "job"
+	RegisterScopePlan = "plan"
+)
+
+// reservedEnvNames are shell variables a registered value would clobber
+var reservedEnvNames = map[string]bool{
+	"PATH":    true,
+	"HOME":    true,
+	"SHELL":   true,
+	"USER":    true,
+	"LOGNAME": true,
+	"PWD":     true,
+	"OLDPWD":  true,
+	"IFS":     true,
+	"TERM":    true,
+	"LANG":    true,
+	"TMPDIR":  true,
+}
+
+// validateRegister checks an action's register and register_scope
+func validateRegister(job *schema.Job, action *schema.Action) error {
+	if action.Register == "" {
+		if action.RegisterScope != "" {
+			return fmt.Errorf("register_scope requires register")
+		}
+		return nil
+	}
+
+	if action.Run == nil {
+		return fmt.Errorf("register is only supported on run actions")
+	}
+	if strings.HasPrefix(action.Register, "HADES_") {
+		return fmt.Errorf("register: cannot define HADES_* environment variables: %s", action.Register)
+	}
+	if !envNamePattern.MatchString(action.Register) {
+		return fmt.Errorf("register: invalid variable name %q", action.Register)
+	}
+	if reservedEnvNames[action.Register] {
+		return fmt.Errorf("register: %q is a reserved shell variable", action.Register)
+	}
+	if _, ok := job.Env[action.Register]; ok {
+		return fmt.Errorf("register: %q is already defined in the job's env", action.Register)
+	}
+
+	switch action.RegisterScope {
+	case "", RegisterScopeJob, RegisterScopePlan:
+		return nil
+	default:
+		return fmt.Errorf("register_scope must be %q or %q, got %q", RegisterScopeJob, RegisterScopePlan, action.RegisterScope)
+	}
+}
+
+// validateStepRegisters checks that plan-scope variables registered by the
+// step's job (or jobs it includes) don't clash with the plan's or any step's
+// env, which would make their value depend on which one is applied last
+func validateStepRegisters(file *schema.File, plan schema.Plan, step schema.Step) error {
+	for _, register := range JobRegisters(file, step.Job) {
+		for _, name := range []string{register, register + "_STDERR", register + "_RC"} {
+			if _, ok := plan.Env[name]; ok {
+				return fmt.Errorf("register: %q is already defined in the plan's env", name)
+			}
+			for _, other := range PlanSteps(plan) {
+				if _, ok := other.Env[name]; ok {
+					return fmt.Errorf("register: %q is already defined in the env of step %q", name, other.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// JobRegisters returns the names registered with register_scope: plan by a
+// job and the jobs it includes
+func JobRegisters(file *schema.File, jobName string) []string {
+	return jobRegisters(file, jobName, make(map[string]bool))
+}
+
+func jobRegisters(file *schema.File, jobName string, seen map[string]bool) []string {
+	if seen[jobName] {
+		return nil
+	}
+	seen[jobName] = true
+
+	var names []string
+	for _, action := range file.Jobs[jobName].Actions {
+		if action.Register != "" && action.RegisterScope == RegisterScopePlan {
+			names = append(names, action.Register)
+		}
+		if action.Job != nil {
+			names = append(names, jobRegisters(file, action.Job.Name, seen)...)
+		}
+	}
+	return names
+}
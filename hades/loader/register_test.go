@@ -0,0 +1,102 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+func TestValidateRegister(t *testing.T) {
+	run := &schema.ActionRun{Cmd: "readlink /opt/app/current"}
+	job := schema.Job{Env: map[string]schema.Env{"VERSION": {}}}
+
+	tests := []struct {
+		name    string
+		action  schema.Action
+		wantErr bool
+	}{
+		{name: "none", action: schema.Action{Run: run}},
+		{name: "job scope", action: schema.Action{Run: run, Register: "CURRENT"}},
+		{name: "plan scope", action: schema.Action{Run: run, Register: "CURRENT", RegisterScope: "plan"}},
+		{name: "not a run action", action: schema.Action{Mkdir: &schema.ActionMkdir{Path: "/tmp"}, Register: "X"}, wantErr: true},
+		{name: "HADES_ name", action: schema.Action{Run: run, Register: "HADES_X"}, wantErr: true},
+		{name: "reserved name", action: schema.Action{Run: run, Register: "PATH"}, wantErr: true},
+		{name: "invalid name", action: schema.Action{Run: run, Register: "current-release"}, wantErr: true},
+		{name: "shadows job env", action: schema.Action{Run: run, Register: "VERSION"}, wantErr: true},
+		{name: "unknown scope", action: schema.Action{Run: run, Register: "CURRENT", RegisterScope: "global"}, wantErr: true},
+		{name: "scope without register", action: schema.Action{Run: run, RegisterScope: "plan"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRegister(&job, &tt.action)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateStepRegisters(t *testing.T) {
+	file := &schema.File{
+		Jobs: map[string]schema.Job{
+			"probe": {Actions: []schema.Action{
+				{Run: &schema.ActionRun{Cmd: "readlink /opt/app/current"}, Register: "CURRENT", RegisterScope: "plan"},
+			}},
+			"wrapper": {Actions: []schema.Action{
+				{Job: &schema.ActionJob{Name: "probe"}},
+			}},
+			"local": {Actions: []schema.Action{
+				{Run: &schema.ActionRun{Cmd: "date"}, Register: "NOW"},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		plan    schema.Plan
+		wantErr bool
+	}{
+		{
+			name: "no clash",
+			plan: schema.Plan{Steps: []schema.Step{{Name: "probe", Job: "probe"}}},
+		},
+		{
+			name:    "clashes with plan env",
+			plan:    schema.Plan{Env: map[string]string{"CURRENT": "v1"}, Steps: []schema.Step{{Name: "probe", Job: "probe"}}},
+			wantErr: true,
+		},
+		{
+			name: "clashes with another step's env",
+			plan: schema.Plan{Steps: []schema.Step{
+				{Name: "probe", Job: "probe"},
+				{Name: "deploy", Job: "local", Env: map[string]string{"CURRENT": "v1"}},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "registered by included job",
+			plan:    schema.Plan{Env: map[string]string{"CURRENT": "v1"}, Steps: []schema.Step{{Name: "wrap", Job: "wrapper"}}},
+			wantErr: true,
+		},
+		{
+			name: "job scope may reuse env names",
+			plan: schema.Plan{Env: map[string]string{"NOW": "x"}, Steps: []schema.Step{{Name: "local", Job: "local"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStepRegisters(file, tt.plan, tt.plan.Steps[0])
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package loader
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+// Retry describes how often an action is attempted
+type Retry struct {
+	Attempts int           // Total attempts (1 = no retries)
+	Delay    time.Duration // Wait before the first retry
+	Backoff  float64       // Delay multiplier for each further retry
+}
+
+// ActionRetry returns the retry settings of an action
+func ActionRetry(action *schema.Action) (Retry, error) {
+	retry := Retry{Attempts: action.Retries + 1, Backoff: action.Backoff}
+	if action.Retries < 0 {
+		return retry, fmt.Errorf("invalid retries %d: must not be negative", action.Retries)
+	}
+
+	if action.Delay != "" {
+		delay, err := time.ParseDuration(action.Delay)
+		if err != nil {
+			return retry, fmt.Errorf("invalid delay %q: %w", action.Delay, err)
+		}
+		if delay < 0 {
+			return retry, fmt.Errorf("invalid delay %q: must not be negative", action.Delay)
+		}
+		retry.Delay = delay
+	}
+
+	if retry.Backoff == 0 {
+		retry.Backoff = 1
+	}
+	if retry.Backoff < 1 {
+		return retry, fmt.Errorf("invalid backoff %g: must be at least 1", action.Backoff)
+	}
+
+	return retry, nil
+}
+
+// DelayBefore returns the wait before the given attempt (1-based)
+func (r Retry) DelayBefore(attempt int) time.Duration {
+	if attempt <= 1 {
+		return 0
+	}
+
+	delay := float64(r.Delay)
+	for i := 2; i < attempt; i++ {
+		delay *= r.Backoff
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,57 @@
+package loader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+func TestRetry_DelayBefore(t *testing.T) {
+	tests := []struct {
+		name   string
+		action schema.Action
+		want   []time.Duration // Delay before attempts 1, 2, 3, 4
+	}{
+		{name: "no delay", action: schema.Action{Retries: 3}, want: []time.Duration{0, 0, 0, 0}},
+		{name: "constant", action: schema.Action{Retries: 3, Delay: "5s"}, want: []time.Duration{0, 5 * time.Second, 5 * time.Second, 5 * time.Second}},
+		{name: "exponential", action: schema.Action{Retries: 3, Delay: "1s", Backoff: 2}, want: []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, err := ActionRetry(&tt.action)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if retry.Attempts != 4 {
+				t.Errorf("Expected 4 attempts, got %d", retry.Attempts)
+			}
+			for i, want := range tt.want {
+				if got := retry.DelayBefore(i + 1); got != want {
+					t.Errorf("Attempt %d: expected %s, got %s", i+1, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestActionRetry_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		action schema.Action
+	}{
+		{name: "negative retries", action: schema.Action{Retries: -1}},
+		{name: "invalid delay", action: schema.Action{Retries: 1, Delay: "soon"}},
+		{name: "negative delay", action: schema.Action{Retries: 1, Delay: "-1s"}},
+		{name: "shrinking backoff", action: schema.Action{Retries: 1, Delay: "1s", Backoff: 0.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ActionRetry(&tt.action); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package loader
+
+import "github.com/SoftKiwiGames/hades/hades/schema"
+
+// RollbackStep returns the step that runs a step's rollback job, with the
+// step's env and parallelism
+func RollbackStep(step schema.Step) schema.Step {
+	return schema.Step{
+		Name:        step.Name + " (rollback)",
+		Job:         step.Rollback,
+		Env:         step.Env,
+		Parallelism: step.Parallelism,
+	}
+}
+
+// PlanSteps returns every step a plan may run: its steps, their batch check
+// jobs and rollbacks, on_failure and always
+func PlanSteps(plan schema.Plan) []schema.Step {
+	steps := append([]schema.Step{}, plan.Steps...)
+	for _, step := range plan.Steps {
+		if step.BatchCheck != nil && step.BatchCheck.Job != "" {
+			steps = append(steps, BatchCheckStep(step))
+		}
+		if step.Rollback != "" {
+			steps = append(steps, RollbackStep(step))
+		}
+	}
+	steps = append(steps, plan.OnFailure...)
+	return append(steps, plan.Always...)
+}
@@ -0,0 +1,69 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestStep_UnmarshalRollout(t *testing.T) {
+	tests := []struct {
+		name            string
+		yaml            string
+		wantParallelism schema.Parallelism
+		wantLimit       string
+	}{
+		{name: "number", yaml: "parallelism: 5\nlimit: 1\n", wantParallelism: "5", wantLimit: "1"},
+		{name: "percentage", yaml: "parallelism: \"10%\"\nlimit: \"10%\"\n", wantParallelism: "10%", wantLimit: "10%"},
+		{name: "stages", yaml: "parallelism: [1, 10%, 50%, 100%]\n", wantParallelism: "1, 10%, 50%, 100%"},
+		{name: "stages block", yaml: "parallelism:\n  - 1\n  - \"25%\"\n", wantParallelism: "1, 25%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var step schema.Step
+			if err := yaml.Unmarshal([]byte(tt.yaml), &step); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if step.Parallelism != tt.wantParallelism {
+				t.Errorf("Expected %q, got %q", tt.wantParallelism, step.Parallelism)
+			}
+			if step.Limit != tt.wantLimit {
+				t.Errorf("Expected %q, got %q", tt.wantLimit, step.Limit)
+			}
+		})
+	}
+}
+
+func TestValidate_Rollout(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    schema.Step
+		wantErr bool
+	}{
+		{name: "defaults", step: schema.Step{}},
+		{name: "stages", step: schema.Step{Parallelism: "1, 10%, 100%", Limit: "50%", MaxFailPerBatch: "10%"}},
+		{name: "invalid stage", step: schema.Step{Parallelism: "1, none"}, wantErr: true},
+		{name: "invalid limit", step: schema.Step{Limit: "150%"}, wantErr: true},
+		{name: "invalid batch budget", step: schema.Step{MaxFailPerBatch: "-1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.step.Name = "deploy"
+			tt.step.Job = "deploy"
+			file := &schema.File{
+				Jobs:  map[string]schema.Job{"deploy": {}},
+				Plans: map[string]schema.Plan{"deploy": {Steps: []schema.Step{tt.step}}},
+			}
+			err := New().Validate(file)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
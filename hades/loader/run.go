@@ -0,0 +1,37 @@
+package loader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateRun checks the extended options of a run action
+func validateRun(job *schema.Job, run *schema.ActionRun) error {
+	if strings.TrimSpace(run.Cmd) == "" {
+		return fmt.Errorf("run: cmd is required")
+	}
+
+	for name := range run.Env {
+		if strings.HasPrefix(name, "HADES_") {
+			return fmt.Errorf("run: cannot define HADES_* environment variables: %s", name)
+		}
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("run: invalid environment variable name %q", name)
+		}
+	}
+
+	if run.Stdin != nil && (run.Stdin.Src == "") == (run.Stdin.Artifact == "") {
+		return fmt.Errorf("run: stdin needs exactly one of src or artifact")
+	}
+
+	if job.Local && run.User != "" {
+		return fmt.Errorf("run: user is not supported for local jobs")
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"gopkg.in/yaml.v3"
+)
+
+func TestActionRun_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want schema.ActionRun
+	}{
+		{name: "shorthand", yaml: "run: echo hello", want: schema.ActionRun{Cmd: "echo hello"}},
+		{name: "block shorthand", yaml: "run: |\n  echo one\n  echo two\n", want: schema.ActionRun{Cmd: "echo one\necho two\n"}},
+		{name: "extended", yaml: "run:\n  cmd: ./migrate\n  cwd: /opt/app\n  shell: bash -euo pipefail\n  user: app\n", want: schema.ActionRun{Cmd: "./migrate", Cwd: "/opt/app", Shell: "bash -euo pipefail", User: "app"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var action schema.Action
+			if err := yaml.Unmarshal([]byte(tt.yaml), &action); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if action.Run == nil {
+				t.Fatal("Expected run to be set")
+			}
+			if action.Run.Cmd != tt.want.Cmd || action.Run.Cwd != tt.want.Cwd || action.Run.Shell != tt.want.Shell || action.Run.User != tt.want.User {
+				t.Errorf("Expected %+v, got %+v", tt.want, *action.Run)
+			}
+		})
+	}
+}
+
+func TestValidateRun(t *testing.T) {
+	tests := []struct {
+		name    string
+		job     schema.Job
+		run     schema.ActionRun
+		wantErr bool
+	}{
+		{name: "valid", run: schema.ActionRun{Cmd: "true", Env: map[string]string{"APP_ENV": "prod"}}},
+		{name: "missing cmd", run: schema.ActionRun{Cwd: "/tmp"}, wantErr: true},
+		{name: "HADES_ env", run: schema.ActionRun{Cmd: "true", Env: map[string]string{"HADES_HOST_NAME": "x"}}, wantErr: true},
+		{name: "invalid env name", run: schema.ActionRun{Cmd: "true", Env: map[string]string{"APP-ENV": "x"}}, wantErr: true},
+		{name: "stdin without source", run: schema.ActionRun{Cmd: "cat", Stdin: &schema.RunStdin{}}, wantErr: true},
+		{name: "stdin with both sources", run: schema.ActionRun{Cmd: "cat", Stdin: &schema.RunStdin{Src: "a", Artifact: "b"}}, wantErr: true},
+		{name: "user on local job", job: schema.Job{Local: true}, run: schema.ActionRun{Cmd: "true", User: "app"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRun(&tt.job, &tt.run)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
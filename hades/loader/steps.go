@@ -0,0 +1,47 @@
+package loader
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+// SelectSteps returns the names of the plan steps to run, starting at startAt
+// or limited to only. It returns nil when every step runs.
+func SelectSteps(plan *schema.Plan, startAt string, only []string) ([]string, error) {
+	if startAt != "" && len(only) > 0 {
+		return nil, fmt.Errorf("cannot combine a start step with a list of steps")
+	}
+
+	var names []string
+	for _, step := range plan.Steps {
+		names = append(names, step.Name)
+	}
+
+	if startAt != "" {
+		idx := slices.Index(names, startAt)
+		if idx < 0 {
+			return nil, fmt.Errorf("step %q not found in plan", startAt)
+		}
+		return names[idx:], nil
+	}
+
+	if len(only) > 0 {
+		for _, name := range only {
+			if !slices.Contains(names, name) {
+				return nil, fmt.Errorf("step %q not found in plan", name)
+			}
+		}
+		// Keep plan order
+		var selected []string
+		for _, name := range names {
+			if slices.Contains(only, name) {
+				selected = append(selected, name)
+			}
+		}
+		return selected, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,47 @@
+package loader
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+func TestSelectSteps(t *testing.T) {
+	plan := &schema.Plan{
+		Steps: []schema.Step{{Name: "build"}, {Name: "deploy"}, {Name: "verify"}},
+	}
+
+	tests := []struct {
+		name    string
+		startAt string
+		only    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "all", want: nil},
+		{name: "start at", startAt: "deploy", want: []string{"deploy", "verify"}},
+		{name: "only keeps plan order", only: []string{"verify", "build"}, want: []string{"build", "verify"}},
+		{name: "unknown start", startAt: "migrate", wantErr: true},
+		{name: "unknown only", only: []string{"build", "migrate"}, wantErr: true},
+		{name: "both", startAt: "deploy", only: []string{"build"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectSteps(plan, tt.startAt, tt.only)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
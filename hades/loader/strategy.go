@@ -0,0 +1,50 @@
+package loader
+
+import (
+	"fmt"
+
+	"github.com/SoftKiwiGames/hades/hades/rollout"
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+// validateStrategy checks the plan's strategy and that its steps only use
+// options that apply to it
+func validateStrategy(plan schema.Plan) error {
+	switch plan.Strategy {
+	case "", schema.StrategySteps:
+		if plan.Concurrency != "" {
+			return fmt.Errorf("concurrency requires strategy %s", schema.StrategyPerHost)
+		}
+		return nil
+	case schema.StrategyPerHost:
+	default:
+		return fmt.Errorf("unknown strategy %q (expected %s or %s)", plan.Strategy, schema.StrategySteps, schema.StrategyPerHost)
+	}
+
+	if _, err := rollout.ParseConcurrency(plan.Concurrency, 0); err != nil {
+		return err
+	}
+
+	// Hosts don't wait for each other, so there are no steps or batches to coordinate
+	for _, step := range plan.Steps {
+		var option string
+		switch {
+		case step.Needs != nil:
+			option = "needs"
+		case step.Parallelism != "":
+			option = "parallelism (use the plan's concurrency)"
+		case step.MaxFailPerBatch != "":
+			option = "max_fail_per_batch"
+		case step.PauseBetweenBatches != "":
+			option = "pause_between_batches"
+		case step.BatchCheck != nil:
+			option = "batch_check"
+		case step.ConfirmBetweenBatches:
+			option = "confirm_between_batches"
+		default:
+			continue
+		}
+		return fmt.Errorf("step %q: %s does not apply with strategy %s", step.Name, option, schema.StrategyPerHost)
+	}
+	return nil
+}
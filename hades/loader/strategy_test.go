@@ -0,0 +1,37 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+func TestValidateStrategy(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    schema.Plan
+		wantErr bool
+	}{
+		{name: "default", plan: schema.Plan{Steps: []schema.Step{{Name: "a", Parallelism: "1"}}}},
+		{name: "steps", plan: schema.Plan{Strategy: "steps"}},
+		{name: "per host", plan: schema.Plan{Strategy: "per_host", Concurrency: "25%", Steps: []schema.Step{{Name: "a", MaxFail: "1", Limit: "2"}}}},
+		{name: "unknown", plan: schema.Plan{Strategy: "free"}, wantErr: true},
+		{name: "concurrency without per host", plan: schema.Plan{Concurrency: "5"}, wantErr: true},
+		{name: "invalid concurrency", plan: schema.Plan{Strategy: "per_host", Concurrency: "0"}, wantErr: true},
+		{name: "parallelism", plan: schema.Plan{Strategy: "per_host", Steps: []schema.Step{{Name: "a", Parallelism: "1"}}}, wantErr: true},
+		{name: "needs", plan: schema.Plan{Strategy: "per_host", Steps: []schema.Step{{Name: "a"}, {Name: "b", Needs: []string{"a"}}}}, wantErr: true},
+		{name: "batch check", plan: schema.Plan{Strategy: "per_host", Steps: []schema.Step{{Name: "a", BatchCheck: &schema.BatchCheck{Job: "health"}}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStrategy(tt.plan)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package loader
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+// ActionTimeout returns the timeout for an action: its own timeout, else the
+// job's default. Zero means no timeout ("0" disables a job default).
+func ActionTimeout(job *schema.Job, action *schema.Action) (time.Duration, error) {
+	if action.Timeout != "" {
+		return parseTimeout(action.Timeout)
+	}
+	if job.Timeout != "" {
+		return parseTimeout(job.Timeout)
+	}
+	return 0, nil
+}
+
+func parseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", value, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must not be negative", value)
+	}
+	return timeout, nil
+}
@@ -0,0 +1,46 @@
+package loader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+func TestActionTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		job         string
+		action      string
+		want        time.Duration
+		expectError bool
+	}{
+		{name: "no timeout", want: 0},
+		{name: "job default", job: "5m", want: 5 * time.Minute},
+		{name: "action overrides job", job: "5m", action: "30s", want: 30 * time.Second},
+		{name: "action disables job default", job: "5m", action: "0", want: 0},
+		{name: "invalid", action: "soon", expectError: true},
+		{name: "negative", job: "-1s", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &schema.Job{Timeout: tt.job}
+			action := &schema.Action{Timeout: tt.action}
+
+			got, err := ActionTimeout(job, action)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -95,8 +95,8 @@ func ValidatePlanEnv(file *schema.File, planName string, cliEnv map[string]strin
 		}
 	}
 
-	// Validate each step
-	for i, step := range plan.Steps {
+	// Validate each step, including rollback and cleanup steps
+	for i, step := range PlanSteps(plan) {
 		job, ok := file.Jobs[step.Job]
 		if !ok {
 			return fmt.Errorf("step %q: job %q not found", step.Name, step.Job)
@@ -124,6 +124,9 @@ func ValidatePlanEnv(file *schema.File, planName string, cliEnv map[string]strin
 		if err := ValidateEnvContract(&job, mergedEnv); err != nil {
 			return fmt.Errorf("step %d (%s): %w", i, step.Name, err)
 		}
+		if err := validateIncludedEnv(file, &job, mergedEnv); err != nil {
+			return fmt.Errorf("step %d (%s): %w", i, step.Name, err)
+		}
 	}
 
 	return nil
@@ -0,0 +1,28 @@
+package loader
+
+import (
+	"fmt"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+	"github.com/SoftKiwiGames/hades/hades/selector"
+)
+
+// validateWhen checks that an action condition sets exactly one of if and expr,
+// and that expr parses
+func validateWhen(when *schema.When) error {
+	if (when.If == "") == (when.Expr == "") {
+		return fmt.Errorf("when needs exactly one of if or expr")
+	}
+	if when.Expr == "" {
+		return nil
+	}
+
+	tokens, err := selector.Lex(when.Expr)
+	if err != nil {
+		return fmt.Errorf("when: invalid expression %q: %w", when.Expr, err)
+	}
+	if _, err := selector.Parse(tokens); err != nil {
+		return fmt.Errorf("when: invalid expression %q: %w", when.Expr, err)
+	}
+	return nil
+}
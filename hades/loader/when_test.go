@@ -0,0 +1,34 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/schema"
+)
+
+func TestValidateWhen(t *testing.T) {
+	tests := []struct {
+		name    string
+		when    schema.When
+		wantErr bool
+	}{
+		{name: "shell test", when: schema.When{If: "test -f /etc/app.conf"}},
+		{name: "expression", when: schema.When{Expr: `APP_ENV == "prod" && MIGRATE_RC != "0"`}},
+		{name: "empty", when: schema.When{}, wantErr: true},
+		{name: "both", when: schema.When{If: "true", Expr: `A == "b"`}, wantErr: true},
+		{name: "invalid expression", when: schema.When{Expr: `APP_ENV = "prod"`}, wantErr: true},
+		{name: "unbalanced parentheses", when: schema.When{Expr: `(APP_ENV == "prod"`}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWhen(&tt.when)
+			if tt.wantErr && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
@@ -100,16 +100,17 @@ func (l *Logger) Stderr() io.Writer {
 }
 
 // WriteJobDelimiter writes a job delimiter to the stdout log
-func (l *Logger) WriteJobDelimiter(jobName string, actionType string, actionName string, actionIndex int) error {
+// (actionIndex is e.g. "3", or "3.1" for an action of an included job)
+func (l *Logger) WriteJobDelimiter(jobName string, actionType string, actionName string, actionIndex string) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 
 	// Format action with optional name
-	actionDesc := fmt.Sprintf("[%d] %s", actionIndex, actionType)
+	actionDesc := fmt.Sprintf("[%s] %s", actionIndex, actionType)
 	if actionName != "" {
-		actionDesc = fmt.Sprintf("[%d] %s // %s", actionIndex, actionType, actionName)
+		actionDesc = fmt.Sprintf("[%s] %s // %s", actionIndex, actionType, actionName)
 	}
 
 	delimiter := fmt.Sprintf("\n====================\nJOB: %s, ACTION: %s\nSTARTED: %s\n--------------------\n\n",
@@ -0,0 +1,43 @@
+package rollout
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseMaxFail returns how many hosts may fail before a step is aborted
+// Supports:
+// - Empty string: no failures tolerated
+// - "2": up to 2 hosts may fail
+// - "10%": up to 10% of hosts may fail (rounded down)
+func ParseMaxFail(maxFail string, hostCount int) (int, error) {
+	if maxFail == "" {
+		return 0, nil
+	}
+
+	if strings.HasSuffix(maxFail, "%") {
+		percentStr := strings.TrimSuffix(maxFail, "%")
+		percent, err := strconv.ParseFloat(percentStr, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid percentage format: %s", maxFail)
+		}
+
+		if percent < 0 || percent > 100 {
+			return 0, fmt.Errorf("percentage must be between 0 and 100, got: %.2f", percent)
+		}
+
+		return int(float64(hostCount) * (percent / 100.0)), nil
+	}
+
+	count, err := strconv.Atoi(maxFail)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_fail format: %s (expected number or percentage)", maxFail)
+	}
+
+	if count < 0 {
+		return 0, fmt.Errorf("max_fail must not be negative, got: %d", count)
+	}
+
+	return count, nil
+}
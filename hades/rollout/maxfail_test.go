@@ -0,0 +1,36 @@
+package rollout
+
+import "testing"
+
+func TestParseMaxFail(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxFail   string
+		hostCount int
+		want      int
+		wantErr   bool
+	}{
+		{name: "empty tolerates nothing", maxFail: "", hostCount: 10, want: 0},
+		{name: "count", maxFail: "2", hostCount: 10, want: 2},
+		{name: "zero", maxFail: "0", hostCount: 10, want: 0},
+		{name: "percentage", maxFail: "25%", hostCount: 10, want: 2},
+		{name: "percentage of few hosts rounds down", maxFail: "10%", hostCount: 5, want: 0},
+		{name: "all hosts", maxFail: "100%", hostCount: 7, want: 7},
+		{name: "negative", maxFail: "-1", hostCount: 10, wantErr: true},
+		{name: "percentage over 100", maxFail: "150%", hostCount: 10, wantErr: true},
+		{name: "invalid", maxFail: "some", hostCount: 10, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMaxFail(tt.maxFail, tt.hostCount)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseMaxFail() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseMaxFail() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
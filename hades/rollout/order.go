@@ -0,0 +1,52 @@
+package rollout
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+)
+
+// OrderHosts returns hosts in the order they should be rolled out to
+// Supports:
+// - Empty string or "inventory": the order targets list them in
+// - "sorted": by host name
+// - "reverse": inventory order reversed
+// - "shuffle(42)": shuffled, the same way every time for the same seed
+func OrderHosts(hosts []ssh.Host, order string) ([]ssh.Host, error) {
+	ordered := slices.Clone(hosts)
+
+	switch order {
+	case "", "inventory":
+		return ordered, nil
+	case "sorted":
+		slices.SortFunc(ordered, func(a, b ssh.Host) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		return ordered, nil
+	case "reverse":
+		slices.Reverse(ordered)
+		return ordered, nil
+	}
+
+	seedStr, ok := strings.CutPrefix(order, "shuffle(")
+	if ok {
+		seedStr, ok = strings.CutSuffix(seedStr, ")")
+	}
+	if !ok {
+		return nil, fmt.Errorf("invalid order %q (expected sorted, inventory, reverse or shuffle(seed))", order)
+	}
+	seed, err := strconv.ParseUint(seedStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shuffle seed %q (expected a non-negative integer)", seedStr)
+	}
+
+	rng := rand.New(rand.NewPCG(seed, 0))
+	rng.Shuffle(len(ordered), func(i, j int) {
+		ordered[i], ordered[j] = ordered[j], ordered[i]
+	})
+	return ordered, nil
+}
@@ -0,0 +1,67 @@
+package rollout
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SoftKiwiGames/hades/hades/ssh"
+)
+
+func TestOrderHosts(t *testing.T) {
+	hosts := []ssh.Host{{Name: "web-2"}, {Name: "web-3"}, {Name: "web-1"}}
+
+	tests := []struct {
+		name    string
+		order   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "default keeps inventory order", order: "", want: []string{"web-2", "web-3", "web-1"}},
+		{name: "inventory", order: "inventory", want: []string{"web-2", "web-3", "web-1"}},
+		{name: "sorted", order: "sorted", want: []string{"web-1", "web-2", "web-3"}},
+		{name: "reverse", order: "reverse", want: []string{"web-1", "web-3", "web-2"}},
+		{name: "shuffle without seed", order: "shuffle", wantErr: true},
+		{name: "shuffle with invalid seed", order: "shuffle(abc)", wantErr: true},
+		{name: "unknown", order: "random", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OrderHosts(hosts, tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("OrderHosts() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(names(got), tt.want) {
+				t.Errorf("OrderHosts() = %v, want %v", names(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderHosts_ShuffleIsStable(t *testing.T) {
+	var hosts []ssh.Host
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		hosts = append(hosts, ssh.Host{Name: name})
+	}
+
+	first, err := OrderHosts(hosts, "shuffle(42)")
+	if err != nil {
+		t.Fatalf("OrderHosts() error = %v", err)
+	}
+	second, _ := OrderHosts(hosts, "shuffle(42)")
+	if !reflect.DeepEqual(names(first), names(second)) {
+		t.Errorf("OrderHosts() = %v, then %v for the same seed", names(first), names(second))
+	}
+	if hosts[0].Name != "a" {
+		t.Errorf("OrderHosts() modified its input")
+	}
+}
+
+func names(hosts []ssh.Host) []string {
+	var names []string
+	for _, host := range hosts {
+		names = append(names, host.Name)
+	}
+	return names
+}
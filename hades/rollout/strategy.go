@@ -9,8 +9,10 @@ import (
 )
 
 type Strategy struct {
-	Parallelism int
-	Limit       int
+	Parallelism int   // Hosts per batch; for staged rollouts, the size of the last stage
+	Stages      []int // Batch sizes of a staged rollout, in order; the last one repeats
+
+	MaxFailPerBatch string // Hosts that may fail in one batch (N or N% of the batch, "" = no budget)
 }
 
 // ParseStrategy parses a parallelism string and returns a Strategy
@@ -19,6 +21,7 @@ type Strategy struct {
 // - "1": serial execution
 // - "5": 5 hosts at a time
 // - "40%": 40% of hosts at a time
+// - "1, 10%, 50%, 100%": staged rollout, 1 host, then 10% of hosts, ...
 func ParseStrategy(parallelism string, hostCount int) (*Strategy, error) {
 	strategy := &Strategy{}
 
@@ -28,16 +31,38 @@ func ParseStrategy(parallelism string, hostCount int) (*Strategy, error) {
 		return strategy, nil
 	}
 
+	if !strings.Contains(parallelism, ",") {
+		count, err := parseBatchSize("parallelism", parallelism, hostCount)
+		if err != nil {
+			return nil, err
+		}
+		strategy.Parallelism = count
+		return strategy, nil
+	}
+
+	for i, stage := range strings.Split(parallelism, ",") {
+		count, err := parseBatchSize("parallelism", strings.TrimSpace(stage), hostCount)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d: %w", i+1, err)
+		}
+		strategy.Stages = append(strategy.Stages, count)
+	}
+	strategy.Parallelism = strategy.Stages[len(strategy.Stages)-1]
+	return strategy, nil
+}
+
+// parseBatchSize parses a number of hosts or a percentage of hostCount (at least 1)
+func parseBatchSize(what, size string, hostCount int) (int, error) {
 	// Check for percentage
-	if strings.HasSuffix(parallelism, "%") {
-		percentStr := strings.TrimSuffix(parallelism, "%")
+	if strings.HasSuffix(size, "%") {
+		percentStr := strings.TrimSuffix(size, "%")
 		percent, err := strconv.ParseFloat(percentStr, 64)
 		if err != nil {
-			return nil, fmt.Errorf("invalid percentage format: %s", parallelism)
+			return 0, fmt.Errorf("invalid percentage format: %s", size)
 		}
 
 		if percent <= 0 || percent > 100 {
-			return nil, fmt.Errorf("percentage must be between 0 and 100, got: %.2f", percent)
+			return 0, fmt.Errorf("percentage must be between 0 and 100, got: %.2f", percent)
 		}
 
 		// Calculate number of hosts (at least 1)
@@ -45,22 +70,20 @@ func ParseStrategy(parallelism string, hostCount int) (*Strategy, error) {
 		if count < 1 {
 			count = 1
 		}
-		strategy.Parallelism = count
-		return strategy, nil
+		return count, nil
 	}
 
 	// Parse as integer
-	count, err := strconv.Atoi(parallelism)
+	count, err := strconv.Atoi(size)
 	if err != nil {
-		return nil, fmt.Errorf("invalid parallelism format: %s (expected number or percentage)", parallelism)
+		return 0, fmt.Errorf("invalid %s format: %s (expected number or percentage)", what, size)
 	}
 
 	if count < 1 {
-		return nil, fmt.Errorf("parallelism must be at least 1, got: %d", count)
+		return 0, fmt.Errorf("%s must be at least 1, got: %d", what, count)
 	}
 
-	strategy.Parallelism = count
-	return strategy, nil
+	return count, nil
 }
 
 // CreateBatches splits hosts into batches based on the strategy
@@ -69,26 +92,53 @@ func (s *Strategy) CreateBatches(hosts []ssh.Host) [][]ssh.Host {
 		return nil
 	}
 
-	// Apply limit if specified
-	selectedHosts := hosts
-	if s.Limit > 0 && s.Limit < len(hosts) {
-		selectedHosts = hosts[:s.Limit]
+	// Split into batches, following the stages first
+	var batches [][]ssh.Host
+	for i := 0; i < len(hosts); {
+		size := s.Parallelism
+		if len(batches) < len(s.Stages) {
+			size = s.Stages[len(batches)]
+		}
+		end := i + size
+		if end > len(hosts) {
+			end = len(hosts)
+		}
+		batches = append(batches, hosts[i:end])
+		i = end
 	}
 
-	// If parallelism >= host count, single batch
-	if s.Parallelism >= len(selectedHosts) {
-		return [][]ssh.Host{selectedHosts}
+	return batches
+}
+
+// BatchMaxFail returns how many hosts of a batch of the given size may fail,
+// and false if there is no per-batch budget
+func (s *Strategy) BatchMaxFail(batchSize int) (int, bool) {
+	if s.MaxFailPerBatch == "" {
+		return 0, false
 	}
+	// Validated with ParseMaxFail when the plan was loaded
+	maxFail, _ := ParseMaxFail(s.MaxFailPerBatch, batchSize)
+	return maxFail, true
+}
 
-	// Split into batches
-	var batches [][]ssh.Host
-	for i := 0; i < len(selectedHosts); i += s.Parallelism {
-		end := i + s.Parallelism
-		if end > len(selectedHosts) {
-			end = len(selectedHosts)
-		}
-		batches = append(batches, selectedHosts[i:end])
+// ParseLimit returns how many hosts a step is limited to (0 = all)
+// Supports:
+// - Empty string or "0": no limit
+// - "2": the first 2 hosts
+// - "10%": the first 10% of hosts (rounded down, at least 1)
+func ParseLimit(limit string, hostCount int) (int, error) {
+	if limit == "" || limit == "0" {
+		return 0, nil
 	}
 
-	return batches
+	return parseBatchSize("limit", limit, hostCount)
+}
+
+// ParseConcurrency returns how many hosts may run at once (all if empty)
+func ParseConcurrency(concurrency string, hostCount int) (int, error) {
+	if concurrency == "" {
+		return hostCount, nil
+	}
+
+	return parseBatchSize("concurrency", concurrency, hostCount)
 }
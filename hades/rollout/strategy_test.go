@@ -1,6 +1,7 @@
 package rollout
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/SoftKiwiGames/hades/hades/ssh"
@@ -103,7 +104,6 @@ func TestCreateBatches(t *testing.T) {
 	tests := []struct {
 		name        string
 		parallelism int
-		limit       int
 		hostCount   int
 		wantBatches int
 		wantSizes   []int
@@ -111,7 +111,6 @@ func TestCreateBatches(t *testing.T) {
 		{
 			name:        "single batch - all hosts",
 			parallelism: 10,
-			limit:       0,
 			hostCount:   10,
 			wantBatches: 1,
 			wantSizes:   []int{10},
@@ -119,7 +118,6 @@ func TestCreateBatches(t *testing.T) {
 		{
 			name:        "serial - one at a time",
 			parallelism: 1,
-			limit:       0,
 			hostCount:   10,
 			wantBatches: 10,
 			wantSizes:   []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
@@ -127,7 +125,6 @@ func TestCreateBatches(t *testing.T) {
 		{
 			name:        "two hosts at a time",
 			parallelism: 2,
-			limit:       0,
 			hostCount:   10,
 			wantBatches: 5,
 			wantSizes:   []int{2, 2, 2, 2, 2},
@@ -135,26 +132,23 @@ func TestCreateBatches(t *testing.T) {
 		{
 			name:        "three hosts at a time",
 			parallelism: 3,
-			limit:       0,
 			hostCount:   10,
 			wantBatches: 4,
 			wantSizes:   []int{3, 3, 3, 1},
 		},
 		{
-			name:        "with limit - canary",
+			name:        "single host",
 			parallelism: 1,
-			limit:       1,
-			hostCount:   10,
+			hostCount:   1,
 			wantBatches: 1,
 			wantSizes:   []int{1},
 		},
 		{
-			name:        "with limit and parallelism",
-			parallelism: 2,
-			limit:       5,
-			hostCount:   10,
-			wantBatches: 3,
-			wantSizes:   []int{2, 2, 1},
+			name:        "parallelism above host count",
+			parallelism: 10,
+			hostCount:   5,
+			wantBatches: 1,
+			wantSizes:   []int{5},
 		},
 	}
 
@@ -162,7 +156,6 @@ func TestCreateBatches(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			strategy := &Strategy{
 				Parallelism: tt.parallelism,
-				Limit:       tt.limit,
 			}
 
 			batches := strategy.CreateBatches(hosts[:tt.hostCount])
@@ -180,3 +173,133 @@ func TestCreateBatches(t *testing.T) {
 		})
 	}
 }
+
+func TestParseStrategy_Stages(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallelism string
+		hostCount   int
+		wantSizes   []int
+		wantErr     bool
+	}{
+		{
+			name:        "growing batches",
+			parallelism: "1, 10%, 50%, 100%",
+			hostCount:   20,
+			wantSizes:   []int{1, 2, 10, 7},
+		},
+		{
+			name:        "last stage repeats",
+			parallelism: "1,2",
+			hostCount:   7,
+			wantSizes:   []int{1, 2, 2, 2},
+		},
+		{
+			name:        "fewer hosts than stages",
+			parallelism: "1, 25%, 100%",
+			hostCount:   2,
+			wantSizes:   []int{1, 1},
+		},
+		{
+			name:        "invalid stage",
+			parallelism: "1, 0, 100%",
+			hostCount:   10,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy, err := ParseStrategy(tt.parallelism, tt.hostCount)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseStrategy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+
+			hosts := make([]ssh.Host, tt.hostCount)
+			var sizes []int
+			for _, batch := range strategy.CreateBatches(hosts) {
+				sizes = append(sizes, len(batch))
+			}
+			if !reflect.DeepEqual(sizes, tt.wantSizes) {
+				t.Errorf("CreateBatches() sizes = %v, want %v", sizes, tt.wantSizes)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     string
+		hostCount int
+		want      int
+		wantErr   bool
+	}{
+		{name: "empty is no limit", limit: "", hostCount: 10, want: 0},
+		{name: "zero is no limit", limit: "0", hostCount: 10, want: 0},
+		{name: "count", limit: "3", hostCount: 10, want: 3},
+		{name: "percentage", limit: "25%", hostCount: 20, want: 5},
+		{name: "percentage rounds down but min 1", limit: "10%", hostCount: 5, want: 1},
+		{name: "negative", limit: "-1", hostCount: 10, wantErr: true},
+		{name: "invalid", limit: "some", hostCount: 10, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimit(tt.limit, tt.hostCount)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLimit() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseLimit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchMaxFail(t *testing.T) {
+	strategy := &Strategy{}
+	if _, ok := strategy.BatchMaxFail(10); ok {
+		t.Errorf("BatchMaxFail() without a budget returned ok")
+	}
+
+	strategy.MaxFailPerBatch = "20%"
+	if got, ok := strategy.BatchMaxFail(10); !ok || got != 2 {
+		t.Errorf("BatchMaxFail() = %v, %v, want 2, true", got, ok)
+	}
+}
+
+func TestParseConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency string
+		hostCount   int
+		want        int
+		wantErr     bool
+	}{
+		{name: "empty is all hosts", concurrency: "", hostCount: 10, want: 10},
+		{name: "count", concurrency: "3", hostCount: 10, want: 3},
+		{name: "percentage", concurrency: "25%", hostCount: 20, want: 5},
+		{name: "percentage rounds down but min 1", concurrency: "10%", hostCount: 5, want: 1},
+		{name: "zero", concurrency: "0", hostCount: 10, wantErr: true},
+		{name: "invalid", concurrency: "many", hostCount: 10, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConcurrency(tt.concurrency, tt.hostCount)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseConcurrency() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseConcurrency() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
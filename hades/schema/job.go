@@ -1,34 +1,82 @@
 package schema
 
+import "gopkg.in/yaml.v3"
+
 type Job struct {
-	Local     bool                `yaml:"local"`
-	Guard     *Guard              `yaml:"guard,omitempty"`
-	Env       map[string]Env      `yaml:"env"`
-	Artifacts map[string]Artifact `yaml:"artifacts"`
-	Actions   []Action            `yaml:"actions"`
+	Local      bool                `yaml:"local"`
+	Guard      *Guard              `yaml:"guard,omitempty"`
+	Timeout    string              `yaml:"timeout,omitempty"`     // Default timeout for each action
+	Become     bool                `yaml:"become,omitempty"`      // Run actions through sudo
+	BecomeUser string              `yaml:"become_user,omitempty"` // User to become (default root)
+	ExportEnv  *bool               `yaml:"export_env,omitempty"`  // Export env into commands' environment (default true)
+	Env        map[string]Env      `yaml:"env"`
+	Artifacts  map[string]Artifact `yaml:"artifacts"`
+	Actions    []Action            `yaml:"actions"`
 }
 
 type Guard struct {
 	If string `yaml:"if"`
 }
 
+// When is an action condition: a shell test (like Guard) or an expression over env
+type When struct {
+	If   string `yaml:"if,omitempty"`   // Passes when the command exits 0
+	Expr string `yaml:"expr,omitempty"` // e.g. APP_ENV == "prod" && MIGRATE_RC != "0"
+}
+
 type Artifact struct {
 	Path string `yaml:"path"`
 }
 
 type Action struct {
-	Name     string          `yaml:"name,omitempty"`
-	Run      *ActionRun      `yaml:"run,omitempty"`
-	Copy     *ActionCopy     `yaml:"copy,omitempty"`
-	Template *ActionTemplate `yaml:"template,omitempty"`
-	Mkdir    *ActionMkdir    `yaml:"mkdir,omitempty"`
-	Push     *ActionPush     `yaml:"push,omitempty"`
-	Pull     *ActionPull     `yaml:"pull,omitempty"`
-	Wait     *ActionWait     `yaml:"wait,omitempty"`
-	Gpg      *ActionGpg      `yaml:"gpg,omitempty"`
+	Name          string          `yaml:"name,omitempty"`
+	Timeout       string          `yaml:"timeout,omitempty"`        // Overrides the job's default timeout
+	Become        *bool           `yaml:"become,omitempty"`         // Overrides the job's become
+	BecomeUser    string          `yaml:"become_user,omitempty"`    // Overrides the job's become_user
+	Register      string          `yaml:"register,omitempty"`       // Store run output as ${NAME}, ${NAME_STDERR}, ${NAME_RC}
+	RegisterScope string          `yaml:"register_scope,omitempty"` // job (default) or plan: keep for later steps on the host
+	When          *When           `yaml:"when,omitempty"`           // Skip the action unless the condition holds
+	Retries       int             `yaml:"retries,omitempty"`        // Extra attempts after a failure
+	Delay         string          `yaml:"delay,omitempty"`          // Wait before each retry
+	Backoff       float64         `yaml:"backoff,omitempty"`        // Multiplies the delay after each retry (default 1)
+	Until         string          `yaml:"until,omitempty"`          // Shell test that must pass for an attempt to succeed
+	IgnoreErrors  bool            `yaml:"ignore_errors,omitempty"`  // Continue the job when the action fails
+	Run           *ActionRun      `yaml:"run,omitempty"`
+	Copy          *ActionCopy     `yaml:"copy,omitempty"`
+	Template      *ActionTemplate `yaml:"template,omitempty"`
+	Mkdir         *ActionMkdir    `yaml:"mkdir,omitempty"`
+	Push          *ActionPush     `yaml:"push,omitempty"`
+	Pull          *ActionPull     `yaml:"pull,omitempty"`
+	Wait          *ActionWait     `yaml:"wait,omitempty"`
+	Gpg           *ActionGpg      `yaml:"gpg,omitempty"`
+	Job           *ActionJob      `yaml:"job,omitempty"` // Run another job's actions in place
+}
+
+// ActionRun accepts either a bare command string or the extended form
+type ActionRun struct {
+	Cmd   string            `yaml:"cmd"`
+	Cwd   string            `yaml:"cwd,omitempty"`   // Directory to run cmd in
+	Shell string            `yaml:"shell,omitempty"` // Shell to run cmd with, e.g. "bash -euo pipefail" (default sh)
+	Env   map[string]string `yaml:"env,omitempty"`   // Action-local env, merged over the job's
+	Stdin *RunStdin         `yaml:"stdin,omitempty"` // Fed to cmd's standard input
+	User  string            `yaml:"user,omitempty"`  // Run cmd as this user through sudo
+}
+
+type RunStdin struct {
+	Src      string `yaml:"src,omitempty"`
+	Artifact string `yaml:"artifact,omitempty"`
 }
 
-type ActionRun string
+func (r *ActionRun) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		r.Cmd = value.Value
+		return nil
+	}
+
+	// Decode through an alias so this method isn't called again
+	type plain ActionRun
+	return value.Decode((*plain)(r))
+}
 
 type ActionCopy struct {
 	Src      string `yaml:"src,omitempty"`
@@ -72,3 +120,20 @@ type ActionGpg struct {
 	Mode    uint32 `yaml:"mode,omitempty"`
 	Dearmor bool   `yaml:"dearmor,omitempty"`
 }
+
+// ActionJob accepts either a bare job name or the extended form
+type ActionJob struct {
+	Name string            `yaml:"name"`
+	Env  map[string]string `yaml:"env,omitempty"` // Overrides, expanded against the including job's env
+}
+
+func (j *ActionJob) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		j.Name = value.Value
+		return nil
+	}
+
+	// Decode through an alias so this method isn't called again
+	type plain ActionJob
+	return value.Decode((*plain)(j))
+}
@@ -1,15 +1,70 @@
 package schema
 
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
 type Plan struct {
-	Env   map[string]string `yaml:"env,omitempty"`
-	Steps []Step            `yaml:"steps"`
+	Env       map[string]string `yaml:"env,omitempty"`
+	Steps     []Step            `yaml:"steps"`
+	OnFailure []Step            `yaml:"on_failure,omitempty"` // Run when a step fails, after rollbacks
+	Always    []Step            `yaml:"always,omitempty"`     // Run last, whether the plan failed or not
+
+	Strategy    string `yaml:"strategy,omitempty"`    // steps (default) or per_host
+	Concurrency string `yaml:"concurrency,omitempty"` // Hosts running their steps at once with per_host (N or N%, default all)
 }
 
+// Plan strategies
+const (
+	StrategySteps   = "steps"    // Every host finishes a step before the next step starts
+	StrategyPerHost = "per_host" // Each host runs through its steps on its own
+)
+
 type Step struct {
 	Name        string            `yaml:"name"`
 	Job         string            `yaml:"job"`
 	Targets     []string          `yaml:"targets"`
+	Needs       []string          `yaml:"needs,omitempty"` // Steps that must complete first; without it, the previous step (see loader.StepNeeds)
 	Env         map[string]string `yaml:"env,omitempty"`
-	Parallelism string            `yaml:"parallelism,omitempty"`
-	Limit       int               `yaml:"limit,omitempty"`
+	Parallelism Parallelism       `yaml:"parallelism,omitempty"`
+	Limit       string            `yaml:"limit,omitempty"`    // First N or N% of the hosts
+	Order       string            `yaml:"order,omitempty"`    // sorted, inventory (default), reverse or shuffle(seed)
+	MaxFail     string            `yaml:"max_fail,omitempty"` // Hosts that may fail (N or N%) before the step aborts
+	Rollback    string            `yaml:"rollback,omitempty"` // Job that undoes this step if the plan fails
+
+	MaxFailPerBatch string `yaml:"max_fail_per_batch,omitempty"` // Hosts that may fail (N or N% of the batch) in each batch
+
+	// Gates between batches
+	PauseBetweenBatches   string      `yaml:"pause_between_batches,omitempty"`   // Wait before each batch after the first
+	BatchCheck            *BatchCheck `yaml:"batch_check,omitempty"`             // Must pass on each finished batch
+	ConfirmBetweenBatches bool        `yaml:"confirm_between_batches,omitempty"` // Ask before each batch after the first
+}
+
+// BatchCheck runs a job, or a single command, on the hosts of a finished batch
+type BatchCheck struct {
+	Job     string `yaml:"job,omitempty"`
+	Run     string `yaml:"run,omitempty"`
+	Local   bool   `yaml:"local,omitempty"`   // Run the command on this machine, once per host
+	Retries int    `yaml:"retries,omitempty"` // Extra attempts of the command
+	Delay   string `yaml:"delay,omitempty"`   // Wait before each retry of the command
+}
+
+// Parallelism is a batch size (N or N%), or a list of growing batch sizes
+// for a staged rollout, stored comma-separated
+type Parallelism string
+
+func (p *Parallelism) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*p = Parallelism(value.Value)
+		return nil
+	}
+
+	var stages []string
+	if err := value.Decode(&stages); err != nil {
+		return err
+	}
+	*p = Parallelism(strings.Join(stages, ", "))
+	return nil
 }
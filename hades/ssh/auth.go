@@ -0,0 +1,333 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Authentication methods, tried in the order listed on the host
+const (
+	AuthAgent = "agent" // Keys held by ssh-agent (SSH_AUTH_SOCK)
+	AuthCert  = "cert"  // OpenSSH certificate for the identity file (<key>-cert.pub)
+	AuthKey   = "key"   // Private key from the identity file
+)
+
+// defaultAuthMethods is used when a host doesn't list any methods
+var defaultAuthMethods = []string{AuthAgent, AuthCert, AuthKey}
+
+// defaultIdentityFiles are tried (if present) when a host has no identity_file
+var defaultIdentityFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// ValidAuthMethod reports whether method is a known authentication method
+func ValidAuthMethod(method string) bool {
+	switch method {
+	case AuthAgent, AuthCert, AuthKey:
+		return true
+	}
+	return false
+}
+
+// authMethods builds the SSH auth methods for host.
+// All methods are public key based, and the ssh package only tries each method
+// type once, so the signers are combined into a single callback in host order.
+// Encrypted keys only ask for their passphrase once the server accepts them.
+func (c *client) authMethods(host Host) []ssh.AuthMethod {
+	methods := host.AuthMethods
+	if len(methods) == 0 {
+		methods = defaultAuthMethods
+	}
+
+	return []ssh.AuthMethod{
+		ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			var signers []ssh.Signer
+			var errs []error
+
+			for _, method := range methods {
+				var found []ssh.Signer
+				var err error
+
+				switch method {
+				case AuthAgent:
+					found, err = c.agentSigners()
+				case AuthCert:
+					found, err = c.certSigners(host)
+				case AuthKey:
+					found, err = c.keySigners(host)
+				default:
+					err = fmt.Errorf("unknown auth method %q", method)
+				}
+
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", method, err))
+					continue
+				}
+				signers = append(signers, found...)
+			}
+
+			if len(signers) == 0 {
+				if len(errs) > 0 {
+					return nil, fmt.Errorf("no usable SSH credentials for %s: %w", host.Name, errors.Join(errs...))
+				}
+				return nil, fmt.Errorf("no usable SSH credentials for %s (tried %s)", host.Name, strings.Join(methods, ", "))
+			}
+
+			return signers, nil
+		}),
+	}
+}
+
+// agentSigners returns the keys held by ssh-agent, or none if no agent is running
+func (c *client) agentSigners() ([]ssh.Signer, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if c.agent == nil {
+		sock := os.Getenv("SSH_AUTH_SOCK")
+		if sock == "" {
+			return nil, nil
+		}
+
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to ssh-agent: %w", err)
+		}
+		// Kept open for the rest of the run: signing goes through the agent
+		c.agentConn = conn
+		c.agent = agent.NewClient(conn)
+	}
+
+	signers, err := c.agent.Signers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ssh-agent keys: %w", err)
+	}
+	return signers, nil
+}
+
+// certSigners returns a certificate signer for the host's identity file, if a certificate exists
+func (c *client) certSigners(host Host) ([]ssh.Signer, error) {
+	var signers []ssh.Signer
+
+	for _, keyPath := range identityFiles(host) {
+		certPath := host.CertPath
+		if certPath == "" {
+			certPath = keyPath + "-cert.pub"
+		}
+
+		certData, err := os.ReadFile(certPath)
+		if err != nil {
+			if os.IsNotExist(err) && host.CertPath == "" {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read SSH certificate %s: %w", certPath, err)
+		}
+
+		pub, _, _, _, err := ssh.ParseAuthorizedKey(certData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSH certificate %s: %w", certPath, err)
+		}
+		cert, ok := pub.(*ssh.Certificate)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an SSH certificate", certPath)
+		}
+
+		signer, err := c.loadKey(keyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		certSigner, err := ssh.NewCertSigner(cert, signer)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %s does not match key %s: %w", certPath, keyPath, err)
+		}
+		signers = append(signers, certSigner)
+	}
+
+	return signers, nil
+}
+
+// keySigners returns signers for the host's identity file (or the default identities).
+// A default identity that can't be loaded is reported and skipped, so one
+// broken key in ~/.ssh doesn't hide the others.
+func (c *client) keySigners(host Host) ([]ssh.Signer, error) {
+	var signers []ssh.Signer
+	var errs []error
+	for _, keyPath := range identityFiles(host) {
+		signer, err := c.loadKey(keyPath)
+		if err != nil {
+			if host.KeyPath != "" {
+				return nil, err
+			}
+			c.warnKey(keyPath, err)
+			errs = append(errs, err)
+			continue
+		}
+		signers = append(signers, signer)
+	}
+	if len(signers) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return signers, nil
+}
+
+// warnKey reports a default identity that failed to load, once per run
+func (c *client) warnKey(keyPath string, err error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if c.skipped[keyPath] {
+		return
+	}
+	c.skipped[keyPath] = true
+	fmt.Fprintf(os.Stderr, "Warning: skipping SSH key: %v\n", err)
+}
+
+// identityFiles returns the host's identity file, or the default identities that exist
+func identityFiles(host Host) []string {
+	if host.KeyPath != "" {
+		return []string{host.KeyPath}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, name := range defaultIdentityFiles {
+		path := filepath.Join(home, ".ssh", name)
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// loadKey parses a private key. An encrypted key is only decrypted when it
+// first signs, so its passphrase isn't asked while another method may still
+// succeed. Parsed keys are cached so each passphrase is asked at most once per run.
+func (c *client) loadKey(keyPath string) (ssh.Signer, error) {
+	signer, err := c.parseKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without its public key, an encrypted key must be decrypted to be offered
+	if key, ok := signer.(*encryptedKey); ok && key.pub == nil {
+		if _, err := key.decrypt(); err != nil {
+			return nil, err
+		}
+	}
+	return signer, nil
+}
+
+// parseKey returns the cached signer for keyPath, reading it on first use
+func (c *client) parseKey(keyPath string) (ssh.Signer, error) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if signer, ok := c.signers[keyPath]; ok {
+		return signer, nil
+	}
+
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key %s: %w", keyPath, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(keyData)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		key := &encryptedKey{path: keyPath, data: keyData, pub: missing.PublicKey, prompt: promptSecret}
+		if key.pub == nil {
+			// Legacy PEM keys don't carry their public key; the .pub file may
+			if pubData, err := os.ReadFile(keyPath + ".pub"); err == nil {
+				key.pub, _, _, _, _ = ssh.ParseAuthorizedKey(pubData)
+			}
+		}
+		signer, err = key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s: %w", keyPath, err)
+	}
+
+	c.signers[keyPath] = signer
+	return signer, nil
+}
+
+// encryptedKey is a passphrase protected key that asks for its passphrase the
+// first time it signs, i.e. once a server accepted its public key
+type encryptedKey struct {
+	path   string
+	data   []byte
+	pub    ssh.PublicKey // Known without decrypting, or nil
+	prompt func(prompt string) (string, error)
+
+	mu     sync.Mutex // Held while prompting, so the passphrase is asked once
+	signer ssh.Signer // Decrypted key
+}
+
+func (k *encryptedKey) PublicKey() ssh.PublicKey {
+	if k.pub != nil {
+		return k.pub
+	}
+	// Only loaded this way once decrypted
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.signer.PublicKey()
+}
+
+func (k *encryptedKey) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	signer, err := k.decrypt()
+	if err != nil {
+		return nil, err
+	}
+	return signer.Sign(rand, data)
+}
+
+// SignWithAlgorithm lets RSA keys sign with rsa-sha2-* like parsed keys do
+func (k *encryptedKey) SignWithAlgorithm(rand io.Reader, data []byte, algorithm string) (*ssh.Signature, error) {
+	signer, err := k.decrypt()
+	if err != nil {
+		return nil, err
+	}
+	algSigner, ok := signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, fmt.Errorf("SSH key %s does not support algorithm %s", k.path, algorithm)
+	}
+	return algSigner.SignWithAlgorithm(rand, data, algorithm)
+}
+
+// decrypt asks for the passphrase and parses the key, once it succeeds
+func (k *encryptedKey) decrypt() (ssh.Signer, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.signer != nil {
+		return k.signer, nil
+	}
+
+	passphrase, err := k.prompt(fmt.Sprintf("Passphrase for key %s", k.path))
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKeyWithPassphrase(k.data, []byte(passphrase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key %s: %w", k.path, err)
+	}
+	if k.pub != nil && !bytes.Equal(signer.PublicKey().Marshal(), k.pub.Marshal()) {
+		return nil, fmt.Errorf("SSH key %s does not match %s.pub", k.path, k.path)
+	}
+
+	k.signer = signer
+	return signer, nil
+}
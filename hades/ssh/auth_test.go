@@ -0,0 +1,197 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// writeTestKey writes a new unencrypted OpenSSH private key and returns its signer
+func writeTestKey(t *testing.T, path string) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	return signer
+}
+
+func newTestClient() *client {
+	return &client{signers: make(map[string]ssh.Signer), skipped: make(map[string]bool)}
+}
+
+func TestKeySigners_DefaultIdentities(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	want := writeTestKey(t, filepath.Join(home, ".ssh", "id_ed25519"))
+
+	signers, err := newTestClient().keySigners(Host{Name: "web-1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(signers) != 1 {
+		t.Fatalf("Expected 1 signer, got %d", len(signers))
+	}
+	if ssh.FingerprintSHA256(signers[0].PublicKey()) != ssh.FingerprintSHA256(want.PublicKey()) {
+		t.Error("Expected signer for ~/.ssh/id_ed25519")
+	}
+}
+
+func TestKeySigners_SkipsBrokenDefaultIdentity(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.MkdirAll(filepath.Join(home, ".ssh"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".ssh", "id_ed25519"), []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	want := writeTestKey(t, filepath.Join(home, ".ssh", "id_rsa"))
+
+	signers, err := newTestClient().keySigners(Host{Name: "web-1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(signers) != 1 {
+		t.Fatalf("Expected 1 signer, got %d", len(signers))
+	}
+	if ssh.FingerprintSHA256(signers[0].PublicKey()) != ssh.FingerprintSHA256(want.PublicKey()) {
+		t.Error("Expected signer for ~/.ssh/id_rsa")
+	}
+}
+
+func TestKeySigners_MissingExplicitKey(t *testing.T) {
+	_, err := newTestClient().keySigners(Host{Name: "web-1", KeyPath: filepath.Join(t.TempDir(), "missing")})
+	if err == nil {
+		t.Error("Expected error for missing identity_file")
+	}
+}
+
+func TestLoadKey_Cached(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	writeTestKey(t, keyPath)
+	c := newTestClient()
+
+	first, err := c.loadKey(keyPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Removing the file proves the second call doesn't read it again
+	os.Remove(keyPath)
+	second, err := c.loadKey(keyPath)
+	if err != nil {
+		t.Fatalf("Expected cached key, got error: %v", err)
+	}
+	if first != second {
+		t.Error("Expected the same signer instance from cache")
+	}
+}
+
+func TestLoadKey_EncryptedDecryptsOnSign(t *testing.T) {
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	want, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No terminal in tests: loading must not prompt
+	signer, err := newTestClient().loadKey(keyPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ssh.FingerprintSHA256(signer.PublicKey()) != ssh.FingerprintSHA256(want) {
+		t.Error("Expected the key's public key before decrypting")
+	}
+
+	key, ok := signer.(*encryptedKey)
+	if !ok {
+		t.Fatalf("Expected an encrypted key, got %T", signer)
+	}
+	prompts := 0
+	key.prompt = func(string) (string, error) {
+		prompts++
+		return "secret", nil
+	}
+	for i := 0; i < 2; i++ {
+		sig, err := signer.Sign(rand.Reader, []byte("data"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := want.Verify([]byte("data"), sig); err != nil {
+			t.Errorf("Expected a valid signature, got %v", err)
+		}
+	}
+	if prompts != 1 {
+		t.Errorf("Expected 1 passphrase prompt, got %d", prompts)
+	}
+}
+
+func TestCertSigners(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "id_ed25519")
+	userKey := writeTestKey(t, keyPath)
+	caKey := writeTestKey(t, filepath.Join(dir, "ca"))
+
+	cert := &ssh.Certificate{
+		Key:             userKey.PublicKey(),
+		CertType:        ssh.UserCert,
+		KeyId:           "deploy",
+		ValidPrincipals: []string{"deploy"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := cert.SignCert(rand.Reader, caKey); err != nil {
+		t.Fatalf("failed to sign cert: %v", err)
+	}
+	if err := os.WriteFile(keyPath+"-cert.pub", ssh.MarshalAuthorizedKey(cert), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	signers, err := newTestClient().certSigners(Host{Name: "web-1", KeyPath: keyPath})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(signers) != 1 {
+		t.Fatalf("Expected 1 signer, got %d", len(signers))
+	}
+	if _, ok := signers[0].PublicKey().(*ssh.Certificate); !ok {
+		t.Error("Expected certificate signer")
+	}
+
+	// No certificate next to the key is not an error
+	os.Remove(keyPath + "-cert.pub")
+	signers, err = newTestClient().certSigners(Host{Name: "web-1", KeyPath: keyPath})
+	if err != nil || len(signers) != 0 {
+		t.Errorf("Expected no signers and no error, got %d, %v", len(signers), err)
+	}
+}
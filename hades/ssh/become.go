@@ -0,0 +1,132 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// sudoMode is what sudo needs on a host to become a user
+type sudoMode int
+
+const (
+	sudoUnknown sudoMode = iota
+	sudoNoPassword
+	sudoWithPassword
+)
+
+// sudoCommand wraps cmd to run as user. With a password, sudo reads it from
+// the first line of stdin; -k makes sure it always does, even with cached credentials.
+func sudoCommand(cmd string, user string, withPassword bool) string {
+	if withPassword {
+		return Command("sudo", "-k", "-S", "-p", "", "-H", "-u", user, "--", "sh", "-c", cmd)
+	}
+	return Command("sudo", "-n", "-H", "-u", user, "--", "sh", "-c", cmd)
+}
+
+// sudo wraps cmd to run as s.host.Become, prepending the sudo password to stdin when needed
+func (c *client) sudo(ctx context.Context, s *session, cmd string, stdin io.Reader) (string, io.Reader, error) {
+	mode, err := c.sudoMode(ctx, s)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if mode == sudoNoPassword {
+		return sudoCommand(cmd, s.host.Become, false), stdin, nil
+	}
+
+	c.becomeMu.Lock()
+	password := strings.NewReader(*c.becomePassword + "\n")
+	c.becomeMu.Unlock()
+	if stdin != nil {
+		return sudoCommand(cmd, s.host.Become, true), io.MultiReader(password, stdin), nil
+	}
+	return sudoCommand(cmd, s.host.Become, true), password, nil
+}
+
+// sudoMode probes (once per host and user) whether sudo needs a password.
+// The password is prompted for once per run and checked on every host that needs it.
+func (c *client) sudoMode(ctx context.Context, s *session) (sudoMode, error) {
+	key := s.host.connKey() + " as " + s.host.Become
+
+	c.becomeMu.Lock()
+	mode := c.becomeModes[key]
+	c.becomeMu.Unlock()
+	if mode != sudoUnknown {
+		return mode, nil
+	}
+
+	mode = sudoNoPassword
+	err := c.probeSudo(ctx, s, "")
+	if errors.Is(err, errSudoPassword) {
+		password, perr := c.sudoPassword(s.host)
+		if perr != nil {
+			return sudoUnknown, perr
+		}
+
+		mode = sudoWithPassword
+		err = c.probeSudo(ctx, s, password)
+		if errors.Is(err, errSudoPassword) {
+			return sudoUnknown, fmt.Errorf("sudo password rejected on %s", s.host.Name)
+		}
+	}
+	if err != nil {
+		return sudoUnknown, err
+	}
+
+	c.becomeMu.Lock()
+	c.becomeModes[key] = mode
+	c.becomeMu.Unlock()
+	return mode, nil
+}
+
+// sudoPassword returns the sudo password, prompting for it on first use
+func (c *client) sudoPassword(host Host) (string, error) {
+	c.becomeMu.Lock()
+	defer c.becomeMu.Unlock()
+
+	if c.becomePassword == nil {
+		password, err := promptSecret("BECOME password (sudo)")
+		if err != nil {
+			return "", fmt.Errorf("sudo on %s requires a password: %w", host.Name, err)
+		}
+		c.becomePassword = &password
+	}
+	return *c.becomePassword, nil
+}
+
+// errSudoPassword means sudo asked for a password (or rejected the one given)
+var errSudoPassword = errors.New("sudo password required")
+
+// probeSudo runs `true` through sudo (with password, if given), translating
+// sudo's complaints into clear errors
+func (c *client) probeSudo(ctx context.Context, s *session, password string) error {
+	var stdin io.Reader
+	if password != "" {
+		stdin = strings.NewReader(password + "\n")
+	}
+
+	var stderr bytes.Buffer
+	err := s.runRaw(ctx, sudoCommand("true", s.host.Become, password != ""), stdin, io.Discard, &stderr)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return err
+	}
+
+	msg := strings.TrimSpace(stderr.String())
+	switch {
+	case strings.Contains(msg, "must have a tty"):
+		return fmt.Errorf("sudo on %s requires a TTY (requiretty in sudoers); allow %s to run sudo without one", s.host.Name, s.host.User)
+	case strings.Contains(msg, "password is required"), strings.Contains(msg, "incorrect password"), strings.Contains(msg, "Sorry, try again"):
+		return errSudoPassword
+	case msg != "":
+		return fmt.Errorf("sudo to %s on %s failed: %s", s.host.Become, s.host.Name, msg)
+	default:
+		return fmt.Errorf("sudo to %s on %s failed: %w", s.host.Become, s.host.Name, err)
+	}
+}
@@ -0,0 +1,19 @@
+package ssh
+
+import "testing"
+
+func TestSudoCommand(t *testing.T) {
+	tests := []struct {
+		withPassword bool
+		want         string
+	}{
+		{false, `sudo -n -H -u postgres -- sh -c 'echo '\''hi'\'''`},
+		{true, `sudo -k -S -p '' -H -u postgres -- sh -c 'echo '\''hi'\'''`},
+	}
+
+	for _, tt := range tests {
+		if got := sudoCommand("echo 'hi'", "postgres", tt.withPassword); got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+}
@@ -3,9 +3,13 @@ package ssh
 import (
 	"context"
 	"fmt"
-	"os"
+	"net"
+	"strconv"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 type Client interface {
@@ -14,75 +18,190 @@ type Client interface {
 }
 
 type Host struct {
-	Name    string
-	Address string
-	User    string
-	KeyPath string
-	Port    int
+	Name          string
+	Address       string
+	User          string
+	KeyPath       string
+	Port          int
+	HostKeyPolicy string   // strict (default), tofu or off
+	KnownHosts    string   // Per-project known_hosts file, consulted before ~/.ssh/known_hosts
+	Fingerprints  []string // Pinned host key fingerprints (SHA256:...), bypass known_hosts
+	CertPath      string   // OpenSSH certificate, defaults to KeyPath + "-cert.pub"
+	AuthMethods   []string // Ordered auth methods (agent, cert, key), defaults to all
+	Jump          *Host    // Bastion to dial through (may itself have a Jump)
+
+	ConnectTimeout time.Duration // Limit for TCP connect and SSH handshake (0 = none)
+	KeepAlive      time.Duration // Interval between keepalive requests (0 = disabled)
+
+	// Set per action by the executor
+	Become string            // Run commands as this user through sudo ("" = login user)
+	Env    map[string]string // Exported into every command's environment
+}
+
+// addr returns the host's dial address (host:port)
+func (h Host) addr() string {
+	port := h.Port
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(h.Address, strconv.Itoa(port))
+}
+
+// connKey identifies a connection, including the bastion chain it goes through
+func (h Host) connKey() string {
+	key := fmt.Sprintf("%s@%s", h.User, h.addr())
+	if h.Jump != nil {
+		key += " via " + h.Jump.connKey()
+	}
+	return key
 }
 
 type client struct {
-	connections map[string]*ssh.Client
+	mu          sync.Mutex              // Guards connections and dialing
+	connections map[string]*pooledConn  // Live connections by connKey
+	dialing     map[string]*pendingDial // In-flight dials by connKey
+
+	knownHostsMu sync.Mutex // Guards known_hosts reads and TOFU writes
+
+	authMu    sync.Mutex            // Guards agent and parsed keys
+	agent     agent.ExtendedAgent   // Lazily connected ssh-agent
+	agentConn net.Conn              // Connection backing agent
+	signers   map[string]ssh.Signer // Parsed private keys by path
+	skipped   map[string]bool       // Default identities already reported as unusable
+
+	becomeMu       sync.Mutex          // Guards sudo probing and the password prompt
+	becomePassword *string             // Sudo password, prompted once per run
+	becomeModes    map[string]sudoMode // Probed sudo requirements by host and user
 }
 
 func NewClient() Client {
 	return &client{
-		connections: make(map[string]*ssh.Client),
+		connections: make(map[string]*pooledConn),
+		dialing:     make(map[string]*pendingDial),
+		signers:     make(map[string]ssh.Signer),
+		skipped:     make(map[string]bool),
+		becomeModes: make(map[string]sudoMode),
 	}
 }
 
 func (c *client) Connect(ctx context.Context, host Host) (Session, error) {
-	// Check if we already have a connection to this host
-	key := fmt.Sprintf("%s@%s", host.User, host.Address)
-	if conn, ok := c.connections[key]; ok {
-		return newSession(conn, host)
+	conn, err := c.connect(ctx, host)
+	if err != nil {
+		return nil, err
 	}
+	return newSession(c, conn, host)
+}
 
-	// Read private key
-	keyData, err := os.ReadFile(host.KeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read SSH key %s: %w", host.KeyPath, err)
+// connect returns a pooled connection to host, dialing it (and its bastions) if needed.
+// Concurrent callers for the same host share one dial; dead connections are redialed.
+func (c *client) connect(ctx context.Context, host Host) (*ssh.Client, error) {
+	key := host.connKey()
+
+	c.mu.Lock()
+	if pc, ok := c.connections[key]; ok {
+		if pc.alive() {
+			c.mu.Unlock()
+			return pc.client, nil
+		}
+		// Connection dropped since last use: reconnect
+		delete(c.connections, key)
 	}
 
-	signer, err := ssh.ParsePrivateKey(keyData)
+	pending, ok := c.dialing[key]
+	if !ok {
+		pending = &pendingDial{done: make(chan struct{})}
+		c.dialing[key] = pending
+		// The dial is shared, so it must not fail every waiter when the ctx
+		// of whoever started it is cancelled; the connect timeout bounds it
+		go c.dialShared(context.WithoutCancel(ctx), key, host, pending)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-pending.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if pending.err != nil {
+		return nil, pending.err
+	}
+	return pending.conn.client, nil
+}
+
+// dialShared dials host for every caller waiting on pending and pools the connection
+func (c *client) dialShared(ctx context.Context, key string, host Host, pending *pendingDial) {
+	// Without a caller to cancel it, connecting always needs a timeout.
+	// Authentication isn't bounded by it; waiters can still give up.
+	if host.ConnectTimeout <= 0 {
+		host.ConnectTimeout = DefaultConnectTimeout
+	}
+
+	pending.conn, pending.err = c.dial(ctx, host)
+
+	c.mu.Lock()
+	delete(c.dialing, key)
+	if pending.err == nil {
+		c.connections[key] = pending.conn
+	}
+	c.mu.Unlock()
+	close(pending.done)
+}
+
+// dial opens a new SSH connection to host
+func (c *client) dial(ctx context.Context, host Host) (*pooledConn, error) {
+	addr := host.addr()
+
+	hostKeyCallback, err := c.hostKeyCallback(host)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse SSH key: %w", err)
+		return nil, fmt.Errorf("failed to set up host key verification for %s: %w", host.Name, err)
 	}
 
 	// Configure SSH client
 	config := &ssh.ClientConfig{
-		User: host.User,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // TODO: Add host key verification
+		User:            host.User,
+		Auth:            c.authMethods(host),
+		HostKeyCallback: hostKeyCallback,
 	}
 
-	// Connect to the host
-	port := host.Port
-	if port == 0 {
-		port = 22
+	// Prefer key types we already know for this host
+	if len(host.Fingerprints) == 0 && host.HostKeyPolicy != HostKeyOff {
+		if files, err := knownHostsFiles(host); err == nil {
+			c.knownHostsMu.Lock()
+			config.HostKeyAlgorithms = knownHostKeyAlgorithms(files, addr)
+			c.knownHostsMu.Unlock()
+		}
 	}
-	addr := fmt.Sprintf("%s:%d", host.Address, port)
 
-	conn, err := ssh.Dial("tcp", addr, config)
+	netConn, err := c.dialTCP(ctx, host, addr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+		return nil, err
 	}
 
-	// Store connection for reuse
-	c.connections[key] = conn
+	conn, err := handshake(ctx, netConn, addr, config, host.ConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
 
-	return newSession(conn, host)
+	return newPooledConn(conn, host.KeepAlive), nil
 }
 
 func (c *client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	var firstErr error
 	for key, conn := range c.connections {
-		if err := conn.Close(); err != nil && firstErr == nil {
+		if err := conn.client.Close(); err != nil && firstErr == nil {
 			firstErr = err
 		}
 		delete(c.connections, key)
 	}
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	if c.agentConn != nil {
+		c.agentConn.Close()
+		c.agentConn = nil
+		c.agent = nil
+	}
 	return firstErr
 }
@@ -0,0 +1,59 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Quote quotes s as a single POSIX shell word so paths and values can be
+// interpolated into remote commands. Plain words are left as they are, and a
+// leading "~/" stays unquoted so it still expands to the home directory.
+func Quote(s string) string {
+	if rest, ok := strings.CutPrefix(s, "~/"); ok {
+		if rest == "" {
+			return "~/"
+		}
+		return "~/" + Quote(rest)
+	}
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_@%+=:,./-") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Command builds a shell command from name and args, quoting every argument
+func Command(name string, args ...string) string {
+	words := make([]string, 0, len(args)+1)
+	words = append(words, name)
+	for _, arg := range args {
+		words = append(words, Quote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// Mode formats a permission mode as a chmod argument
+func Mode(mode uint32) string {
+	return fmt.Sprintf("%o", mode)
+}
+
+// ExitCode returns the exit status of a command run by a Session: 0 when err
+// is nil, -1 when the command didn't exit normally (killed, not started)
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var remoteErr *ssh.ExitError
+	if errors.As(err, &remoteErr) {
+		return remoteErr.ExitStatus()
+	}
+	var localErr *exec.ExitError
+	if errors.As(err, &localErr) {
+		return localErr.ExitCode()
+	}
+	return -1
+}
@@ -0,0 +1,66 @@
+package ssh
+
+import (
+	"os/exec"
+	"testing"
+)
+
+// hostilePaths are values that break or inject commands when left unquoted
+var hostilePaths = []string{
+	"simple",
+	"with space",
+	"it's",
+	`"double"`,
+	"$HOME",
+	"$(touch pwned)",
+	"`touch pwned`",
+	"a; touch pwned",
+	"a && touch pwned",
+	"a | cat",
+	"*",
+	"-rf",
+	"line\nbreak",
+	"",
+}
+
+func TestQuote(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, want := range hostilePaths {
+		cmd := exec.Command("sh", "-c", "printf %s "+Quote(want))
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", want, err)
+		}
+		if string(out) != want {
+			t.Errorf("Expected %q, got %q", want, out)
+		}
+	}
+}
+
+func TestQuote_Tilde(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/etc/app.conf", "/etc/app.conf"},
+		{"~/app.conf", "~/app.conf"},
+		{"~/my app.conf", "~/'my app.conf'"},
+		{"~root", "'~root'"},
+	}
+
+	for _, tt := range tests {
+		if got := Quote(tt.in); got != tt.want {
+			t.Errorf("Quote(%q): expected %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+func TestCommand(t *testing.T) {
+	got := Command("chmod", Mode(0644), "--", "/etc/my app/config.yml")
+	want := "chmod 644 -- '/etc/my app/config.yml'"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
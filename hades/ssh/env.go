@@ -0,0 +1,66 @@
+package ssh
+
+import (
+	"sort"
+	"strings"
+)
+
+// validEnvName reports whether name can be exported by a POSIX shell
+func validEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, r := range name {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// envNames returns the exportable names in env, sorted
+func envNames(env map[string]string) []string {
+	var names []string
+	for name := range env {
+		if validEnvName(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// envPrelude returns an `export` line that sets env for the command that
+// follows it ("" when there is nothing to export). sshd usually rejects
+// Setenv requests, so the environment travels inside the command instead.
+func envPrelude(env map[string]string) string {
+	names := envNames(env)
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("export")
+	for _, name := range names {
+		b.WriteString(" " + name + "=" + quoteValue(env[name]))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// quoteValue quotes v for an assignment; unlike Quote it keeps "~/" literal
+func quoteValue(v string) string {
+	if strings.HasPrefix(v, "~") {
+		return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+	}
+	return Quote(v)
+}
+
+// environ returns env as KEY=value pairs for exec.Cmd.Env
+func environ(env map[string]string) []string {
+	var pairs []string
+	for _, name := range envNames(env) {
+		pairs = append(pairs, name+"="+env[name])
+	}
+	return pairs
+}
@@ -0,0 +1,46 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"testing"
+)
+
+func TestEnvPrelude_RoundTrip(t *testing.T) {
+	env := map[string]string{"HADES_HOST_NAME": "web-1", "HOME_DIR": "~/app", "EMPTY": ""}
+	for i, value := range hostilePaths {
+		env["VALUE_"+string(rune('A'+i))] = value
+	}
+	env["not-valid"] = "skipped"
+
+	for _, name := range envNames(env) {
+		script := envPrelude(env) + `printf %s "$` + name + `"`
+		out, err := exec.Command("sh", "-c", script).Output()
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %v", name, err)
+		}
+		if string(out) != env[name] {
+			t.Errorf("Expected %q, got %q", env[name], out)
+		}
+	}
+}
+
+func TestEnvPrelude_Empty(t *testing.T) {
+	if got := envPrelude(map[string]string{"1BAD": "x"}); got != "" {
+		t.Errorf("Expected empty prelude, got %q", got)
+	}
+}
+
+func TestLocalSession_RunExportsEnv(t *testing.T) {
+	session := &localSession{host: Host{Env: map[string]string{"VERSION": "1.2.3; rm -rf /"}}}
+
+	var stdout bytes.Buffer
+	if err := session.Run(context.Background(), `printf %s "$VERSION"`, &stdout, io.Discard); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := stdout.String(); got != "1.2.3; rm -rf /" {
+		t.Errorf("Expected %q, got %q", "1.2.3; rm -rf /", got)
+	}
+}
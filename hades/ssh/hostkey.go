@@ -0,0 +1,216 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Host key checking policies
+const (
+	HostKeyStrict = "strict" // Fail on unknown or changed host keys (default)
+	HostKeyTOFU   = "tofu"   // Trust on first use: record unknown keys, fail on changed keys
+	HostKeyOff    = "off"    // Accept any host key (not recommended)
+)
+
+// ValidHostKeyPolicy reports whether policy is a known host key checking policy.
+// Empty string is valid and means the default (strict).
+func ValidHostKeyPolicy(policy string) bool {
+	switch policy {
+	case "", HostKeyStrict, HostKeyTOFU, HostKeyOff:
+		return true
+	}
+	return false
+}
+
+// NormalizeFingerprint returns fingerprint in the "SHA256:..." form printed by ssh-keygen -l
+func NormalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.TrimSpace(fingerprint)
+	if !strings.HasPrefix(fingerprint, "SHA256:") {
+		fingerprint = "SHA256:" + fingerprint
+	}
+	return fingerprint
+}
+
+// userKnownHostsFile returns the path to ~/.ssh/known_hosts
+func userKnownHostsFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ssh", "known_hosts"), nil
+}
+
+// knownHostsFiles returns the known_hosts files consulted for host, in lookup order.
+// The first file is the one new keys are recorded to in TOFU mode.
+func knownHostsFiles(host Host) ([]string, error) {
+	var files []string
+	if host.KnownHosts != "" {
+		files = append(files, host.KnownHosts)
+	}
+
+	userFile, err := userKnownHostsFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate user known_hosts: %w", err)
+	}
+	if userFile != host.KnownHosts {
+		files = append(files, userFile)
+	}
+
+	return files, nil
+}
+
+// loadKnownHosts builds a known_hosts callback from the files that exist
+func loadKnownHosts(files []string) (ssh.HostKeyCallback, error) {
+	var existing []string
+	for _, f := range files {
+		if _, err := os.Stat(f); err == nil {
+			existing = append(existing, f)
+		}
+	}
+
+	cb, err := knownhosts.New(existing...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+	return cb, nil
+}
+
+// hostKeyCallback builds the host key verification callback for host
+func (c *client) hostKeyCallback(host Host) (ssh.HostKeyCallback, error) {
+	// Pinned fingerprints take precedence over known_hosts
+	if len(host.Fingerprints) > 0 {
+		return pinnedHostKeyCallback(host.Fingerprints), nil
+	}
+
+	policy := host.HostKeyPolicy
+	if policy == "" {
+		policy = HostKeyStrict
+	}
+
+	if policy == HostKeyOff {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	files, err := knownHostsFiles(host)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		// Serialize lookups so keys recorded by one connection are seen by the next
+		c.knownHostsMu.Lock()
+		defer c.knownHostsMu.Unlock()
+
+		check, err := loadKnownHosts(files)
+		if err != nil {
+			return err
+		}
+
+		err = check(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		fingerprint := ssh.FingerprintSHA256(key)
+
+		if len(keyErr.Want) > 0 {
+			known := keyErr.Want[0]
+			return fmt.Errorf("host key mismatch for %s: server sent %s %s, but %s:%d has %s (possible man-in-the-middle attack)",
+				hostname, key.Type(), fingerprint, known.Filename, known.Line, ssh.FingerprintSHA256(known.Key))
+		}
+
+		if policy != HostKeyTOFU {
+			return fmt.Errorf("unknown host key for %s (%s %s); add it to %s or set host_key_checking: tofu",
+				hostname, key.Type(), fingerprint, files[0])
+		}
+
+		if err := appendKnownHost(files[0], hostname, key); err != nil {
+			return fmt.Errorf("failed to record host key for %s: %w", hostname, err)
+		}
+		return nil
+	}, nil
+}
+
+// pinnedHostKeyCallback accepts only keys matching one of the given fingerprints
+func pinnedHostKeyCallback(fingerprints []string) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		fingerprint := ssh.FingerprintSHA256(key)
+		for _, pinned := range fingerprints {
+			if NormalizeFingerprint(pinned) == fingerprint {
+				return nil
+			}
+		}
+		return fmt.Errorf("host key for %s (%s %s) does not match any pinned fingerprint", hostname, key.Type(), fingerprint)
+	}
+}
+
+// appendKnownHost records key for hostname in the given known_hosts file
+func appendKnownHost(path string, hostname string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// knownHostKeyAlgorithms returns the host key algorithms recorded for addr in
+// known_hosts, so the server is asked for a key type we can actually verify.
+// Returns nil when the host is unknown (let the server pick).
+func knownHostKeyAlgorithms(files []string, addr string) []string {
+	check, err := loadKnownHosts(files)
+	if err != nil {
+		return nil
+	}
+
+	// Probe with a throwaway key: the mismatch error lists the known keys
+	probe, err := ssh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+	if err != nil {
+		return nil
+	}
+
+	var keyErr *knownhosts.KeyError
+	if err := check(addr, &net.TCPAddr{}, probe); !errors.As(err, &keyErr) {
+		return nil
+	}
+
+	var algorithms []string
+	seen := make(map[string]bool)
+	add := func(algo string) {
+		if !seen[algo] {
+			seen[algo] = true
+			algorithms = append(algorithms, algo)
+		}
+	}
+	for _, known := range keyErr.Want {
+		if known.Key.Type() == ssh.KeyAlgoRSA {
+			// RSA keys are negotiated with SHA-2 signature algorithms
+			add(ssh.KeyAlgoRSASHA512)
+			add(ssh.KeyAlgoRSASHA256)
+		}
+		add(known.Key.Type())
+	}
+	return algorithms
+}
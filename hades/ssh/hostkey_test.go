@@ -0,0 +1,117 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("failed to wrap key: %v", err)
+	}
+	return key
+}
+
+var testRemote = &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 22}
+
+func TestHostKeyCallback_StrictRejectsUnknown(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	c := &client{}
+
+	cb, err := c.hostKeyCallback(Host{Name: "web-1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = cb("192.0.2.10:22", testRemote, newTestHostKey(t))
+	if err == nil || !strings.Contains(err.Error(), "unknown host key") {
+		t.Errorf("Expected unknown host key error, got %v", err)
+	}
+}
+
+func TestHostKeyCallback_TOFURecordsAndDetectsChange(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	c := &client{}
+
+	host := Host{Name: "web-1", HostKeyPolicy: HostKeyTOFU, KnownHosts: knownHosts}
+	cb, err := c.hostKeyCallback(host)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	key := newTestHostKey(t)
+	if err := cb("192.0.2.10:22", testRemote, key); err != nil {
+		t.Fatalf("Expected first use to be trusted, got %v", err)
+	}
+
+	data, err := os.ReadFile(knownHosts)
+	if err != nil {
+		t.Fatalf("Expected key to be recorded: %v", err)
+	}
+	if !strings.Contains(string(data), "192.0.2.10") {
+		t.Errorf("Expected recorded line for 192.0.2.10, got %q", string(data))
+	}
+
+	// Same key is accepted again
+	if err := cb("192.0.2.10:22", testRemote, key); err != nil {
+		t.Errorf("Expected recorded key to be accepted, got %v", err)
+	}
+
+	// Different key is rejected even in TOFU mode
+	err = cb("192.0.2.10:22", testRemote, newTestHostKey(t))
+	if err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("Expected host key mismatch error, got %v", err)
+	}
+
+	// Algorithms are narrowed to the recorded key type
+	algorithms := knownHostKeyAlgorithms([]string{knownHosts}, "192.0.2.10:22")
+	if len(algorithms) != 1 || algorithms[0] != ssh.KeyAlgoED25519 {
+		t.Errorf("Expected [%s], got %v", ssh.KeyAlgoED25519, algorithms)
+	}
+}
+
+func TestHostKeyCallback_PinnedFingerprint(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	c := &client{}
+	key := newTestHostKey(t)
+
+	// Pin without the SHA256: prefix to check normalization
+	pinned := strings.TrimPrefix(ssh.FingerprintSHA256(key), "SHA256:")
+	cb, err := c.hostKeyCallback(Host{Name: "web-1", Fingerprints: []string{pinned}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := cb("192.0.2.10:22", testRemote, key); err != nil {
+		t.Errorf("Expected pinned key to be accepted, got %v", err)
+	}
+
+	if err := cb("192.0.2.10:22", testRemote, newTestHostKey(t)); err == nil {
+		t.Error("Expected unpinned key to be rejected")
+	}
+}
+
+func TestValidHostKeyPolicy(t *testing.T) {
+	for _, policy := range []string{"", HostKeyStrict, HostKeyTOFU, HostKeyOff} {
+		if !ValidHostKeyPolicy(policy) {
+			t.Errorf("Expected %q to be valid", policy)
+		}
+	}
+	if ValidHostKeyPolicy("ask") {
+		t.Error("Expected \"ask\" to be invalid")
+	}
+}
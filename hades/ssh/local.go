@@ -17,22 +17,38 @@ func NewLocalClient() Client {
 }
 
 func (c *LocalClient) Connect(ctx context.Context, host Host) (Session, error) {
-	return &localSession{}, nil
+	return &localSession{host: host}, nil
 }
 
 func (c *LocalClient) Close() error {
 	return nil
 }
 
-type localSession struct{}
+type localSession struct {
+	host Host
+}
 
 func (s *localSession) Run(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	return s.RunWithInput(ctx, cmd, nil, stdout, stderr)
+}
+
+func (s *localSession) RunWithInput(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
 	// Run command using shell
 	execCmd := exec.CommandContext(ctx, "sh", "-c", cmd)
+	execCmd.Stdin = stdin
 	execCmd.Stdout = stdout
 	execCmd.Stderr = stderr
+	if len(s.host.Env) > 0 {
+		execCmd.Env = append(os.Environ(), environ(s.host.Env)...)
+	}
+
+	setCancel(execCmd)
+	execCmd.WaitDelay = killGracePeriod
 
 	if err := execCmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("command failed: %w", ctx.Err())
+		}
 		return fmt.Errorf("command failed: %w", err)
 	}
 
@@ -46,8 +62,8 @@ func (s *localSession) CopyFile(ctx context.Context, content io.Reader, destPath
 		return fmt.Errorf("failed to create directory %s: %w", dir, err)
 	}
 
-	// Use atomic write: write to temp file, then move
-	tmpPath := destPath + ".tmp"
+	// Use atomic write: write to temp file next to the destination, then move
+	tmpPath := filepath.Join(dir, tempName(ctx, destPath))
 
 	// Create and write to temp file
 	tmpFile, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(mode))
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package ssh
+
+import "os/exec"
+
+// setCancel kills the command on cancellation; there are no process groups to signal
+func setCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return cmd.Process.Kill()
+	}
+}
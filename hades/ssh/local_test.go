@@ -0,0 +1,63 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalSession_RunCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := (&localSession{}).Run(ctx, "sleep 10", io.Discard, io.Discard)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > killGracePeriod {
+		t.Errorf("Expected command to stop on SIGTERM, took %s", elapsed)
+	}
+}
+
+func TestLocalSession_CopyFile(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "etc", "app", "config.yml")
+	ctx := WithRunID(context.Background(), "hades-20260101-120000")
+
+	if err := (&localSession{}).CopyFile(ctx, strings.NewReader("port: 8080\n"), dst, 0640); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatalf("Expected destination to exist: %v", err)
+	}
+	if string(data) != "port: 8080\n" {
+		t.Errorf("Expected %q, got %q", "port: 8080\n", data)
+	}
+
+	// No temp files are left next to the destination
+	entries, _ := os.ReadDir(filepath.Dir(dst))
+	if len(entries) != 1 {
+		t.Errorf("Expected only the destination file, got %d entries", len(entries))
+	}
+}
+
+func TestTempName(t *testing.T) {
+	ctx := WithRunID(context.Background(), "hades-20260101-120000")
+
+	first := tempName(ctx, "/etc/a/config.yml")
+	second := tempName(ctx, "/etc/a/config.yml")
+	if first == second {
+		t.Errorf("Expected unique temp names, got %q twice", first)
+	}
+	if !strings.HasPrefix(first, ".hades-config.yml.hades-20260101-120000.") || !strings.HasSuffix(first, ".tmp") {
+		t.Errorf("Expected temp name with base name and run ID, got %q", first)
+	}
+}
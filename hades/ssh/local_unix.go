@@ -0,0 +1,17 @@
+//go:build unix
+
+package ssh
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setCancel makes cancellation ask the command's whole process group to stop
+// before it is killed
+func setCancel(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+}
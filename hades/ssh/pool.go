@@ -0,0 +1,183 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Connection defaults applied by the inventory when a host doesn't set them
+const (
+	DefaultConnectTimeout    = 30 * time.Second
+	DefaultKeepAliveInterval = 30 * time.Second
+)
+
+// keepAliveMaxMissed is how many unanswered keepalives close a connection
+const keepAliveMaxMissed = 3
+
+// pooledConn is a cached SSH connection that knows when it has died
+type pooledConn struct {
+	client *ssh.Client
+	done   chan struct{} // Closed when the connection is gone
+}
+
+// newPooledConn wraps client, watching for disconnects and sending keepalives
+func newPooledConn(client *ssh.Client, keepAlive time.Duration) *pooledConn {
+	pc := &pooledConn{
+		client: client,
+		done:   make(chan struct{}),
+	}
+
+	go func() {
+		client.Wait()
+		close(pc.done)
+	}()
+
+	if keepAlive > 0 {
+		go pc.keepAlive(keepAlive)
+	}
+
+	return pc
+}
+
+// alive reports whether the connection is still usable
+func (pc *pooledConn) alive() bool {
+	select {
+	case <-pc.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// keepAlive pings the server periodically and closes the connection once it stops answering
+func (pc *pooledConn) keepAlive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-pc.done:
+			return
+		case <-ticker.C:
+		}
+
+		reply := make(chan error, 1)
+		go func() {
+			_, _, err := pc.client.SendRequest("keepalive@openssh.com", true, nil)
+			reply <- err
+		}()
+
+		select {
+		case err := <-reply:
+			if err != nil {
+				missed = keepAliveMaxMissed
+			} else {
+				missed = 0
+			}
+		case <-time.After(interval):
+			missed++
+		case <-pc.done:
+			return
+		}
+
+		if missed >= keepAliveMaxMissed {
+			// Closing makes Wait return, which marks the connection dead
+			pc.client.Close()
+			return
+		}
+	}
+}
+
+// pendingDial lets concurrent callers wait for a single in-flight dial
+type pendingDial struct {
+	done chan struct{}
+	conn *pooledConn
+	err  error
+}
+
+// dialTCP opens the network connection to addr, directly or through the jump host
+func (c *client) dialTCP(ctx context.Context, host Host, addr string) (net.Conn, error) {
+	// Not under this host's timeout: the jump host's own dial has one, and its
+	// authentication may wait for a passphrase prompt
+	var jumpConn *ssh.Client
+	if host.Jump != nil {
+		var err error
+		jumpConn, err = c.connect(ctx, *host.Jump)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to jump host %s: %w", host.Jump.Name, err)
+		}
+	}
+
+	if host.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, host.ConnectTimeout)
+		defer cancel()
+	}
+
+	if jumpConn == nil {
+		dialer := &net.Dialer{}
+		netConn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+		}
+		return netConn, nil
+	}
+
+	netConn, err := jumpConn.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s via %s: %w", addr, host.Jump.Name, err)
+	}
+	return netConn, nil
+}
+
+// handshake runs the SSH handshake over netConn, aborting on ctx cancellation.
+// The timeout covers the key exchange up to host key verification, but not
+// authentication, which may wait for a passphrase prompt.
+func handshake(ctx context.Context, netConn net.Conn, addr string, config *ssh.ClientConfig, timeout time.Duration) (*ssh.Client, error) {
+	var timedOut atomic.Bool
+	if timeout > 0 {
+		// Closing the connection unblocks a handshake stuck on a silent server
+		timer := time.AfterFunc(timeout, func() {
+			timedOut.Store(true)
+			netConn.Close()
+		})
+		defer timer.Stop()
+
+		// The host key is verified once the key exchange is done
+		verify := config.HostKeyCallback
+		withTimer := *config
+		withTimer.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			timer.Stop()
+			return verify(hostname, remote, key)
+		}
+		config = &withTimer
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		netConn.Close()
+	})
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, config)
+	cancelled := !stop()
+	if cancelled || timedOut.Load() {
+		if err == nil {
+			sshConn.Close()
+		}
+		if cancelled {
+			return nil, fmt.Errorf("SSH handshake with %s aborted: %w", addr, ctx.Err())
+		}
+		return nil, fmt.Errorf("SSH handshake with %s aborted: %w", addr, context.DeadlineExceeded)
+	}
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
@@ -0,0 +1,306 @@
+package ssh
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// testServer is a minimal in-process SSH server that counts connections
+type testServer struct {
+	listener net.Listener
+	hostKey  ssh.Signer
+	accepted atomic.Int32
+	delay    atomic.Int64 // Stall before the handshake, in nanoseconds
+
+	mu    sync.Mutex
+	conns []*ssh.ServerConn
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{listener: listener, hostKey: hostKey}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(hostKey)
+
+	go func() {
+		for {
+			netConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.accepted.Add(1)
+			go func() {
+				time.Sleep(time.Duration(s.delay.Load()))
+				conn, chans, reqs, err := ssh.NewServerConn(netConn, config)
+				if err != nil {
+					return
+				}
+				s.mu.Lock()
+				s.conns = append(s.conns, conn)
+				s.mu.Unlock()
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					ch.Reject(ssh.Prohibited, "not supported")
+				}
+			}()
+		}
+	}()
+
+	t.Cleanup(func() {
+		listener.Close()
+		s.dropAll()
+	})
+	return s
+}
+
+// dropAll closes every server-side connection, simulating a network drop
+func (s *testServer) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *testServer) host() Host {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return Host{
+		Name:           "test",
+		Address:        addr.IP.String(),
+		Port:           addr.Port,
+		User:           "deploy",
+		Fingerprints:   []string{ssh.FingerprintSHA256(s.hostKey.PublicKey())},
+		ConnectTimeout: 5 * time.Second,
+	}
+}
+
+func TestClient_ConcurrentConnectSharesDial(t *testing.T) {
+	server := newTestServer(t)
+	c := NewClient().(*client)
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Connect(context.Background(), server.host()); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := server.accepted.Load(); got != 1 {
+		t.Errorf("Expected 1 connection, got %d", got)
+	}
+}
+
+func TestClient_WaiterOutlivesCancelledDialer(t *testing.T) {
+	server := newTestServer(t)
+	server.delay.Store(int64(300 * time.Millisecond))
+	c := NewClient().(*client)
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.connect(ctx, server.host())
+		first <- err
+	}()
+
+	// Wait for the first caller to start the shared dial
+	for {
+		c.mu.Lock()
+		n := len(c.dialing)
+		c.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := c.connect(context.Background(), server.host())
+		second <- err
+	}()
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected first caller to return context.Canceled, got %v", err)
+	}
+	if err := <-second; err != nil {
+		t.Fatalf("Expected waiter to connect, got error: %v", err)
+	}
+	if got := server.accepted.Load(); got != 1 {
+		t.Errorf("Expected 1 connection, got %d", got)
+	}
+}
+
+func TestClient_ReconnectsAfterDrop(t *testing.T) {
+	server := newTestServer(t)
+	c := NewClient().(*client)
+	defer c.Close()
+
+	conn, err := c.connect(context.Background(), server.host())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	server.dropAll()
+	conn.Wait()
+
+	// Wait for the pool to notice the dead connection
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.mu.Lock()
+		pc := c.connections[server.host().connKey()]
+		c.mu.Unlock()
+		if pc == nil || !pc.alive() || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := c.connect(context.Background(), server.host()); err != nil {
+		t.Fatalf("Expected reconnect, got error: %v", err)
+	}
+	if got := server.accepted.Load(); got != 2 {
+		t.Errorf("Expected 2 connections after reconnect, got %d", got)
+	}
+}
+
+func TestClient_ConnectTimeout(t *testing.T) {
+	// Accepts TCP but never speaks SSH
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(5 * time.Second)
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	host := Host{
+		Name:           "silent",
+		Address:        addr.IP.String(),
+		Port:           addr.Port,
+		HostKeyPolicy:  HostKeyOff,
+		ConnectTimeout: 100 * time.Millisecond,
+	}
+
+	c := NewClient()
+	defer c.Close()
+
+	start := time.Now()
+	_, err = c.Connect(context.Background(), host)
+	if err == nil {
+		t.Fatal("Expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected connect to give up quickly, took %s", elapsed)
+	}
+}
+
+func TestHandshake_TimeoutSparesAuth(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	serverConfig := &ssh.ServerConfig{
+		PublicKeyCallback: func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) { return nil, nil },
+	}
+	serverConfig.AddHostKey(key)
+	go func() {
+		netConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn, chans, reqs, err := ssh.NewServerConn(netConn, serverConfig)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		go ssh.DiscardRequests(reqs)
+		for ch := range chans {
+			ch.Reject(ssh.Prohibited, "not supported")
+		}
+	}()
+
+	netConn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stands in for a passphrase prompt that takes longer than the timeout
+	config := &ssh.ClientConfig{
+		User: "deploy",
+		Auth: []ssh.AuthMethod{ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			time.Sleep(300 * time.Millisecond)
+			return []ssh.Signer{key}, nil
+		})},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}
+
+	conn, err := handshake(context.Background(), netConn, listener.Addr().String(), config, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conn.Close()
+}
+
+func TestHost_ConnKey(t *testing.T) {
+	bastion := Host{User: "ops", Address: "bastion", Port: 2222}
+	host := Host{User: "deploy", Address: "10.0.0.5", Jump: &bastion}
+
+	want := "deploy@10.0.0.5:22 via ops@bastion:2222"
+	if got := host.connKey(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,33 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// promptMu keeps prompts of concurrent dials and sessions from mixing on the terminal
+var promptMu sync.Mutex
+
+// promptSecret asks for a secret on the controlling terminal without echoing it
+func promptSecret(prompt string) (string, error) {
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("%s required but no terminal is available", prompt)
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: ", prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", strings.ToLower(prompt), err)
+	}
+
+	return string(secret), nil
+}
@@ -1,98 +1,196 @@
 package ssh
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"path"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 )
 
+// killGracePeriod is how long a cancelled command gets to exit after SIGTERM
+// before it is killed
+const killGracePeriod = 5 * time.Second
+
 type Session interface {
 	Run(ctx context.Context, cmd string, stdout, stderr io.Writer) error
+	RunWithInput(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error
 	CopyFile(ctx context.Context, content io.Reader, remotePath string, mode uint32) error
 	Close() error
 }
 
 type session struct {
-	conn *ssh.Client
-	host Host
+	client *client
+	conn   *ssh.Client
+	host   Host
 }
 
-func newSession(conn *ssh.Client, host Host) (Session, error) {
+func newSession(client *client, conn *ssh.Client, host Host) (Session, error) {
 	return &session{
-		conn: conn,
-		host: host,
+		client: client,
+		conn:   conn,
+		host:   host,
 	}, nil
 }
 
 func (s *session) Run(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
+	return s.run(ctx, cmd, nil, stdout, stderr)
+}
+
+func (s *session) RunWithInput(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return s.run(ctx, cmd, stdin, stdout, stderr)
+}
+
+// run executes cmd with optional stdin and the host's Env exported,
+// through sudo when the host has Become set
+func (s *session) run(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
+	// Exported inside sudo's shell, since sudo resets the environment
+	cmd = envPrelude(s.host.Env) + cmd
+
+	if s.host.Become != "" {
+		var err error
+		cmd, stdin, err = s.client.sudo(ctx, s, cmd, stdin)
+		if err != nil {
+			return err
+		}
+	}
+	return s.runRaw(ctx, cmd, stdin, stdout, stderr)
+}
+
+// runRaw executes cmd as the login user
+func (s *session) runRaw(ctx context.Context, cmd string, stdin io.Reader, stdout, stderr io.Writer) error {
 	sess, err := s.conn.NewSession()
 	if err != nil {
 		return fmt.Errorf("failed to create SSH session: %w", err)
 	}
 	defer sess.Close()
 
+	sess.Stdin = stdin
 	sess.Stdout = stdout
 	sess.Stderr = stderr
 
 	// Run command
-	if err := sess.Run(cmd); err != nil {
+	if err := sess.Start(cmd); err != nil {
+		return fmt.Errorf("command failed: %w", err)
+	}
+	if err := wait(ctx, sess); err != nil {
 		return fmt.Errorf("command failed: %w", err)
 	}
 
 	return nil
 }
 
-func (s *session) CopyFile(ctx context.Context, content io.Reader, remotePath string, mode uint32) error {
-	// Read all content into memory first
-	data, err := io.ReadAll(content)
-	if err != nil {
-		return fmt.Errorf("failed to read content: %w", err)
+// wait waits for the remote command to exit. When ctx is done the command is
+// sent SIGTERM, then SIGKILL after killGracePeriod, and the session is closed.
+func wait(ctx context.Context, sess *ssh.Session) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- sess.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
 	}
 
-	// Use atomic write: write to temp file, then move
-	tmpPath := fmt.Sprintf("/tmp/hades-%s", path.Base(remotePath))
+	sess.Signal(ssh.SIGTERM)
+	select {
+	case <-done:
+	case <-time.After(killGracePeriod):
+		sess.Signal(ssh.SIGKILL)
+		// Servers that ignore signals still drop the command with the channel
+		sess.Close()
+		<-done
+	}
 
-	// Create new session for file write
-	sess, err := s.conn.NewSession()
-	if err != nil {
-		return fmt.Errorf("failed to create SSH session: %w", err)
+	return ctx.Err()
+}
+
+func (s *session) CopyFile(ctx context.Context, content io.Reader, remotePath string, mode uint32) error {
+	// Create parent directory if it doesn't exist
+	dir := path.Dir(remotePath)
+	if err := s.exec(ctx, Command("mkdir", "-p", "--", dir)); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
 	}
-	defer sess.Close()
 
-	// Write content to temp file using cat
-	stdin, err := sess.StdinPipe()
+	// Use atomic write: write to temp file next to the destination, then move
+	tmpPath := path.Join(dir, tempName(ctx, remotePath))
+
+	// Stream over SFTP, falling back to cat for servers without the subsystem.
+	// SFTP writes as the login user, so become always goes through sudo + cat.
+	err := errSFTPUnavailable
+	if s.host.Become == "" {
+		err = s.writeSFTP(ctx, content, tmpPath, mode)
+	}
+	if errors.Is(err, errSFTPUnavailable) {
+		err = s.writeCat(ctx, content, tmpPath, mode)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get stdin pipe: %w", err)
+		s.removeTemp(ctx, tmpPath)
+		return err
 	}
 
-	writeCmd := fmt.Sprintf("cat > %s && chmod %o %s", tmpPath, mode, tmpPath)
-	if err := sess.Start(writeCmd); err != nil {
-		return fmt.Errorf("failed to start write command: %w", err)
+	// Move temp file to final location (atomic, same filesystem)
+	if err := s.exec(ctx, Command("mv", "-f", "--", tmpPath, remotePath)); err != nil {
+		s.removeTemp(ctx, tmpPath)
+		return fmt.Errorf("failed to move file to final location: %w", err)
 	}
 
-	if _, err := stdin.Write(data); err != nil {
-		stdin.Close()
-		return fmt.Errorf("failed to write data: %w", err)
-	}
-	stdin.Close()
+	return nil
+}
 
-	if err := sess.Wait(); err != nil {
-		return fmt.Errorf("write command failed: %w", err)
+// removeTemp deletes a leftover temp file, even if ctx was cancelled
+func (s *session) removeTemp(ctx context.Context, tmpPath string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), killGracePeriod)
+	defer cancel()
+	s.exec(ctx, Command("rm", "-f", "--", tmpPath))
+}
+
+// exec runs a helper command, including its stderr in the error
+func (s *session) exec(ctx context.Context, cmd string) error {
+	var stderr bytes.Buffer
+	if err := s.Run(ctx, cmd, io.Discard, &stderr); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
 	}
+	return nil
+}
 
-	// Move temp file to final location (atomic)
-	sess2, err := s.conn.NewSession()
+// writeSFTP streams content to remotePath over SFTP
+func (s *session) writeSFTP(ctx context.Context, content io.Reader, remotePath string, mode uint32) error {
+	client, err := newSFTPClient(s.conn)
 	if err != nil {
-		return fmt.Errorf("failed to create SSH session for mv: %w", err)
+		return err
 	}
-	defer sess2.Close()
+	defer client.Close()
 
-	mvCmd := fmt.Sprintf("mv %s %s", tmpPath, remotePath)
-	if err := sess2.Run(mvCmd); err != nil {
-		return fmt.Errorf("failed to move file to final location: %w", err)
+	// SFTP resolves relative paths from the home directory but doesn't expand ~
+	remotePath = strings.TrimPrefix(remotePath, "~/")
+
+	if err := client.writeFile(ctx, remotePath, mode, content); err != nil {
+		return fmt.Errorf("write failed: %w", err)
+	}
+	return nil
+}
+
+// writeCat streams content to remotePath through `cat` on the remote shell
+func (s *session) writeCat(ctx context.Context, content io.Reader, remotePath string, mode uint32) error {
+	writeCmd := "cat > " + Quote(remotePath) + " && " + Command("chmod", Mode(mode), "--", remotePath)
+
+	var stderr bytes.Buffer
+	if err := s.run(ctx, writeCmd, content, io.Discard, &stderr); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("write command failed: %w: %s", err, msg)
+		}
+		return fmt.Errorf("write command failed: %w", err)
 	}
 
 	return nil
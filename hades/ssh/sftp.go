@@ -0,0 +1,105 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// errSFTPUnavailable means the server has no SFTP subsystem; nothing was transferred
+var errSFTPUnavailable = errors.New("sftp subsystem unavailable")
+
+// sftpClient is an SFTP client running on its own SSH session
+type sftpClient struct {
+	*sftp.Client
+	transport io.Closer // Session carrying the subsystem
+}
+
+// newSFTPClient starts the sftp subsystem on a new session.
+// Returns errSFTPUnavailable if the server doesn't offer SFTP.
+func newSFTPClient(conn *ssh.Client) (*sftpClient, error) {
+	sess, err := conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SSH session: %w", err)
+	}
+
+	w, err := sess.StdinPipe()
+	if err != nil {
+		sess.Close()
+		return nil, fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+	r, err := sess.StdoutPipe()
+	if err != nil {
+		sess.Close()
+		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+	}
+
+	if err := sess.RequestSubsystem("sftp"); err != nil {
+		sess.Close()
+		return nil, fmt.Errorf("%w: %v", errSFTPUnavailable, err)
+	}
+
+	// Servers without SFTP often print an error and exit instead of a VERSION
+	client, err := sftp.NewClientPipe(r, w)
+	if err != nil {
+		sess.Close()
+		return nil, fmt.Errorf("%w: %v", errSFTPUnavailable, err)
+	}
+
+	return &sftpClient{Client: client, transport: sess}, nil
+}
+
+// Close drops the session first, so a server that stopped answering can't block it
+func (c *sftpClient) Close() error {
+	err := c.transport.Close()
+	c.Client.Close()
+	return err
+}
+
+// writeFile streams content into remotePath (created or truncated) and sets its mode.
+// A partially written file is removed.
+func (c *sftpClient) writeFile(ctx context.Context, remotePath string, mode uint32, content io.Reader) error {
+	// Closing the session unblocks any pending request
+	stop := context.AfterFunc(ctx, func() {
+		c.transport.Close()
+	})
+	defer stop()
+
+	err := c.writeFileContent(remotePath, mode, content)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		c.Remove(remotePath)
+	}
+	return err
+}
+
+func (c *sftpClient) writeFileContent(remotePath string, mode uint32, content io.Reader) error {
+	f, err := c.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", remotePath, err)
+	}
+
+	// Writes are pipelined; concurrency below 1 means the client's default
+	if _, err := f.ReadFromWithConcurrency(content, 0); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", remotePath, err)
+	}
+
+	// Set explicitly: the mode of a new file is subject to the server's umask
+	if err := f.Chmod(os.FileMode(mode)); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to chmod %s: %w", remotePath, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", remotePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// pipeTransport closes both directions of an in-process SFTP connection
+type pipeTransport []io.Closer
+
+func (p pipeTransport) Close() error {
+	for _, c := range p {
+		c.Close()
+	}
+	return nil
+}
+
+// newTestSFTPClient connects an SFTP client to an in-process server backed by the local filesystem
+func newTestSFTPClient(t *testing.T) *sftpClient {
+	t.Helper()
+
+	toServerR, toServerW := io.Pipe()
+	toClientR, toClientW := io.Pipe()
+
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{toServerR, toClientW})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(toClientR, toServerW)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &sftpClient{Client: client, transport: pipeTransport{toServerW, toClientR}}
+	t.Cleanup(func() {
+		c.Close()
+		server.Close()
+	})
+	return c
+}
+
+// failingReader returns n bytes of data, then an error
+type failingReader struct {
+	n int
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, errors.New("source went away")
+	}
+	n := min(len(p), r.n)
+	r.n -= n
+	return n, nil
+}
+
+func TestSFTPWriteFile(t *testing.T) {
+	client := newTestSFTPClient(t)
+	remotePath := filepath.Join(t.TempDir(), "app.tar.gz")
+
+	// Many chunks, so writes are pipelined
+	want := make([]byte, 4*1024*1024+123)
+	rand.Read(want)
+
+	if err := client.writeFile(context.Background(), remotePath, 0750, bytes.NewReader(want)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(remotePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Expected %d bytes written intact, got %d bytes", len(want), len(got))
+	}
+	info, err := os.Stat(remotePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Errorf("Expected mode 0750, got %o", info.Mode().Perm())
+	}
+}
+
+func TestSFTPWriteFile_OpenFails(t *testing.T) {
+	client := newTestSFTPClient(t)
+	remotePath := filepath.Join(t.TempDir(), "missing", "app.conf")
+
+	if err := client.writeFile(context.Background(), remotePath, 0644, bytes.NewReader([]byte("data"))); err == nil {
+		t.Fatal("Expected error when open fails")
+	}
+}
+
+func TestSFTPWriteFile_FailsMidStream(t *testing.T) {
+	client := newTestSFTPClient(t)
+	dir := t.TempDir()
+	remotePath := filepath.Join(dir, "app.tar.gz")
+
+	// Fails after several WRITE requests are already in flight
+	err := client.writeFile(context.Background(), remotePath, 0644, &failingReader{n: 1024 * 1024})
+	if err == nil {
+		t.Fatal("Expected error when the source fails")
+	}
+	if _, err := os.Stat(remotePath); !os.IsNotExist(err) {
+		t.Errorf("Expected partial file to be removed, got %v", err)
+	}
+
+	// Replies to the failed transfer must not be mistaken for later ones
+	otherPath := filepath.Join(dir, "app.conf")
+	if err := client.writeFile(context.Background(), otherPath, 0644, bytes.NewReader([]byte("data"))); err != nil {
+		t.Fatalf("Expected client to stay usable, got error: %v", err)
+	}
+	if got, _ := os.ReadFile(otherPath); string(got) != "data" {
+		t.Errorf("Expected %q, got %q", "data", got)
+	}
+}
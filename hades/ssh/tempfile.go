@@ -0,0 +1,29 @@
+package ssh
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+)
+
+type runIDKey struct{}
+
+// WithRunID tags ctx with the run ID, which CopyFile puts in temp file names
+func WithRunID(ctx context.Context, runID string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, runID)
+}
+
+// tempName returns a unique temp file name for an atomic write of dst.
+// It lives next to dst so the final rename stays on one filesystem.
+func tempName(ctx context.Context, dst string) string {
+	var random [4]byte
+	rand.Read(random[:])
+
+	name := ".hades-" + path.Base(dst)
+	if runID, _ := ctx.Value(runIDKey{}).(string); runID != "" {
+		name += "." + runID
+	}
+	return fmt.Sprintf("%s.%s.tmp", name, hex.EncodeToString(random[:]))
+}
@@ -0,0 +1,124 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Step and run statuses
+const (
+	StatusPending     = "pending"
+	StatusRunning     = "running"
+	StatusCompleted   = "completed"
+	StatusFailed      = "failed"
+	StatusInterrupted = "interrupted"
+)
+
+// State is the progress of a plan run, saved as logs/<runID>/state.json
+type State struct {
+	RunID   string            `json:"run_id"`
+	Plan    string            `json:"plan"`
+	Status  string            `json:"status"`
+	Targets []string          `json:"targets,omitempty"` // CLI targets
+	Env     map[string]string `json:"env,omitempty"`     // CLI env, before ${VAR} expansion
+	Steps   []Step            `json:"steps"`
+
+	Vars map[string]map[string]string `json:"vars,omitempty"` // Variables registered with register_scope: plan, by host
+}
+
+// Step is the progress of one plan step
+type Step struct {
+	Name      string   `json:"name"`
+	Status    string   `json:"status"`
+	Hosts     []string `json:"hosts,omitempty"`     // Resolved hosts, in the order they run
+	Completed []string `json:"completed,omitempty"` // Hosts that completed the job, batch by batch
+	Failed    []string `json:"failed,omitempty"`    // Hosts whose job failed
+}
+
+// New creates the state of a run that has not started any step yet
+func New(runID, plan string, targets []string, env map[string]string, steps []string) *State {
+	s := &State{
+		RunID:   runID,
+		Plan:    plan,
+		Status:  StatusRunning,
+		Targets: targets,
+		Env:     env,
+	}
+	for _, name := range steps {
+		s.Steps = append(s.Steps, Step{Name: name, Status: StatusPending})
+	}
+	return s
+}
+
+// Path returns the state file of a run
+func Path(runID string) string {
+	return filepath.Join("logs", runID, "state.json")
+}
+
+// Load reads the state of an earlier run
+func Load(runID string) (*State, error) {
+	data, err := os.ReadFile(Path(runID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run %q not found (no %s)", runID, Path(runID))
+		}
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", Path(runID), err)
+	}
+	return &s, nil
+}
+
+// Save writes the state, replacing the previous file atomically. The file is
+// only readable by the user, as registered variables may hold secrets.
+func (s *State) Save() error {
+	path := Path(s.RunID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
+
+// Step returns the progress of the named step, or nil if the run has none
+func (s *State) Step(name string) *Step {
+	for i := range s.Steps {
+		if s.Steps[i].Name == name {
+			return &s.Steps[i]
+		}
+	}
+	return nil
+}
+
+// NextStep returns the first step that has not completed ("" if all have)
+func (s *State) NextStep() string {
+	for _, step := range s.Steps {
+		if step.Status != StatusCompleted {
+			return step.Name
+		}
+	}
+	return ""
+}
+
+// Done reports whether host already completed the step
+func (s *Step) Done(host string) bool {
+	return slices.Contains(s.Completed, host)
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	s := New("hades-1", "deploy", []string{"canary"}, map[string]string{"VERSION": "v1"}, []string{"build", "deploy"})
+	s.Steps[0].Status = StatusCompleted
+	s.Steps[0].Hosts = []string{"app-1", "app-2"}
+	s.Steps[0].Completed = []string{"app-1", "app-2"}
+	s.Vars = map[string]map[string]string{"app-1": {"PREVIOUS": "v0", "PREVIOUS_RC": "0"}}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(Path("hades-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %o", info.Mode().Perm())
+	}
+
+	loaded, err := Load("hades-1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, s) {
+		t.Errorf("Expected %+v, got %+v", s, loaded)
+	}
+	if got := loaded.NextStep(); got != "deploy" {
+		t.Errorf("Expected %q, got %q", "deploy", got)
+	}
+	if !loaded.Step("build").Done("app-2") {
+		t.Errorf("Expected app-2 to have completed build")
+	}
+
+	if _, err := Load("hades-2"); err == nil {
+		t.Errorf("Expected error for unknown run, got nil")
+	}
+}
+
+func TestNextStep(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		want     string
+	}{
+		{name: "not started", statuses: []string{StatusPending, StatusPending}, want: "a"},
+		{name: "failed", statuses: []string{StatusCompleted, StatusFailed}, want: "b"},
+		{name: "interrupted", statuses: []string{StatusCompleted, StatusInterrupted}, want: "b"},
+		{name: "completed", statuses: []string{StatusCompleted, StatusCompleted}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New("run", "plan", nil, nil, []string{"a", "b"})
+			for i, status := range tt.statuses {
+				s.Steps[i].Status = status
+			}
+			if got := s.NextStep(); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
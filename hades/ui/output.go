@@ -101,6 +101,17 @@ func (o *Output) PlanFailed(step, host string, err error) {
 	o.Info("Error: %v", err)
 }
 
+// PlanInterrupted prints where an interrupted plan stopped
+func (o *Output) PlanInterrupted(step string, hosts []string) {
+	fmt.Fprintf(o.stderr, "%s■%s Plan interrupted\n", ctc.ForegroundBlue, ctc.Reset)
+	if step != "" {
+		o.Info("Interrupted step: %s", step)
+	}
+	if len(hosts) > 0 {
+		o.Info("Interrupted hosts: %s", strings.Join(hosts, ", "))
+	}
+}
+
 func (o *Output) DotRed() string {
 	// ⏺
 	return fmt.Sprint(ctc.ForegroundRed, "•", ctc.Reset)